@@ -0,0 +1,83 @@
+// Command dnsinfo prints the DNS records for the host of a URL.
+//
+//	dnsinfo https://homestarrunner.com/toons
+//	dnsinfo -json -server 1.1.1.1:53 boot.dev
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/dnsinfo"
+)
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	server := flag.String("server", "", "nameserver to ask, e.g. 1.1.1.1:53 (default: the system's)")
+	timeout := flag.Duration("timeout", 5*time.Second, "how long all lookups may take")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: dnsinfo [flags] URL")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var r dnsinfo.Resolver
+	if *server != "" {
+		r = dnsinfo.NewResolver(*server)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	report, err := dnsinfo.Lookup(ctx, r, flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dnsinfo:", err)
+		os.Exit(1)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+	printReport(os.Stdout, report)
+}
+
+func printReport(w io.Writer, r *dnsinfo.Report) {
+	fmt.Fprintf(w, "host: %s\n", r.Host)
+	row := func(typ, value string) {
+		fmt.Fprintf(w, "%-6s %s\n", typ, value)
+	}
+	if r.CNAME != "" {
+		row("CNAME", r.CNAME)
+	}
+	for _, a := range r.A {
+		row("A", a)
+	}
+	for _, a := range r.AAAA {
+		row("AAAA", a)
+	}
+	for _, mx := range r.MX {
+		row("MX", strconv.Itoa(int(mx.Pref))+" "+mx.Host)
+	}
+	for _, txt := range r.TXT {
+		row("TXT", strconv.Quote(txt))
+	}
+	for _, ns := range r.NS {
+		row("NS", ns)
+	}
+	for _, typ := range slices.Sorted(maps.Keys(r.Errors)) {
+		fmt.Fprintf(w, "error looking up %s: %s\n", typ, r.Errors[typ])
+	}
+}
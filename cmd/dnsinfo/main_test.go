@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/JavierLU90/http_clients_go/dnsinfo"
+)
+
+func TestPrintReport(t *testing.T) {
+	report := &dnsinfo.Report{
+		Host:  "www.jello.test",
+		CNAME: "jello.test.",
+		A:     []string{"192.0.2.1"},
+		AAAA:  []string{"2001:db8::1"},
+		MX:    []dnsinfo.MX{{Host: "mail.jello.test.", Pref: 10}},
+		TXT:   []string{`say "hi"`},
+		NS:    []string{"ns1.jello.test."},
+		Errors: map[string]string{
+			"TXT":  "timeout",
+			"AAAA": "server misbehaving",
+			"MX":   "refused",
+			"A":    "timeout",
+		},
+	}
+	want := `host: www.jello.test
+CNAME  jello.test.
+A      192.0.2.1
+AAAA   2001:db8::1
+MX     10 mail.jello.test.
+TXT    "say \"hi\""
+NS     ns1.jello.test.
+error looking up A: timeout
+error looking up AAAA: server misbehaving
+error looking up MX: refused
+error looking up TXT: timeout
+`
+	// Map order changes from run to run, so print a few times.
+	for range 10 {
+		var b strings.Builder
+		printReport(&b, report)
+		if b.String() != want {
+			t.Fatalf("printReport =\n%s\nwant\n%s", b.String(), want)
+		}
+	}
+}
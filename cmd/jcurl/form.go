@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+)
+
+// addFormArg adds a curl -F argument to m: "name=value" for a field,
+// "name=@path" for a file or "name=<path" for a field read from a file,
+// optionally followed by ";type=content/type" and ";filename=name".
+// The caller closes the returned file, if any.
+func addFormArg(m *jello.Multipart, arg string) (*os.File, error) {
+	name, value, ok := strings.Cut(arg, "=")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid form argument %q, want name=value", arg)
+	}
+	switch {
+	case strings.HasPrefix(value, "@"):
+		path, params, _ := strings.Cut(value[1:], ";")
+		filename := filepath.Base(path)
+		contentType := ""
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(param, "=")
+			switch strings.TrimSpace(k) {
+			case "type":
+				contentType = v
+			case "filename":
+				filename = v
+			}
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		if contentType == "" {
+			m.File(name, filename, f)
+		} else {
+			m.FileType(name, filename, contentType, f)
+		}
+		return f, nil
+	case strings.HasPrefix(value, "<"):
+		data, err := os.ReadFile(value[1:])
+		if err != nil {
+			return nil, err
+		}
+		m.Field(name, string(data))
+	default:
+		m.Field(name, value)
+	}
+	return nil, nil
+}
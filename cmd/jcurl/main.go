@@ -0,0 +1,252 @@
+// Command jcurl is a small curl look-alike built on the jello client, for
+// trying out the requests from the curl chapter.
+//
+//	jcurl https://jsonplaceholder.typicode.com/users/1
+//	jcurl -X POST -H "Content-Type: application/json" -d '{"key1":"value1"}' http://example.com/resource
+//	jcurl -o /dev/null -w "dns: %{time_namelookup}s total: %{time_total}s\n" https://api.jello.com/projects
+//	jcurl --resolve api.jello.com:443:127.0.0.1 https://api.jello.com/projects
+//	jcurl -b cookies.json -c cookies.json https://api.jello.com/login
+//	jcurl -F comment=broken -F attachment=@screenshot.png https://api.jello.com/issues/1/attachments
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+)
+
+// listFlags collects a flag that can be repeated.
+type listFlags []string
+
+func (l *listFlags) String() string { return strings.Join(*l, ", ") }
+
+func (l *listFlags) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+type options struct {
+	method    string
+	headers   listFlags
+	data      string
+	form      listFlags
+	output    string
+	include   bool
+	writeOut  string
+	maxTime   time.Duration
+	follow    bool
+	maxRedirs int
+	resolve   listFlags
+	cookies   string
+	jar       string
+}
+
+func main() {
+	var o options
+	flag.StringVar(&o.method, "X", "", "request method (default GET, or POST with -d)")
+	flag.Var(&o.headers, "H", "extra header, e.g. \"X-API-Key: 123\" (repeatable)")
+	flag.StringVar(&o.data, "d", "", "request body; @file reads it from a file")
+	flag.Var(&o.form, "F", "multipart form field, name=value, name=@file or name=<file (repeatable)")
+	flag.StringVar(&o.output, "o", "", "write the body to this file instead of stdout")
+	flag.BoolVar(&o.include, "i", false, "include the response status line and headers in the output")
+	flag.StringVar(&o.writeOut, "w", "", "print this after the transfer, with %{variables} like curl; @file reads it from a file")
+	flag.DurationVar(&o.maxTime, "m", 30*time.Second, "maximum time the whole request may take")
+	flag.BoolVar(&o.follow, "L", false, "follow redirects")
+	flag.IntVar(&o.maxRedirs, "max-redirs", jello.DefaultMaxRedirects, "how many redirects -L follows; 0 follows none and -1 any number")
+	flag.Var(&o.resolve, "resolve", "use these addresses for host:port, as host:port:addr[,addr] (repeatable)")
+	flag.StringVar(&o.cookies, "b", "", "send cookies saved in this file")
+	flag.StringVar(&o.jar, "c", "", "save cookies to this file after the request")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: jcurl [flags] URL")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), o); err != nil {
+		fmt.Fprintln(os.Stderr, "jcurl:", err)
+		os.Exit(1)
+	}
+}
+
+// clientOptions turns the flags into jello client options.
+func clientOptions(o options) ([]jello.Option, error) {
+	opts := []jello.Option{
+		jello.WithMiddleware(jello.StageObserve, jello.CaptureTimings()),
+	}
+	// Like curl, only follow redirects with -L.
+	redirects := jello.RedirectPolicy{Mode: jello.RedirectNever}
+	if o.follow {
+		redirects = jello.RedirectPolicy{Mode: jello.RedirectFollow, MaxRedirects: maxRedirects(o.maxRedirs)}
+	}
+	opts = append(opts, jello.WithRedirectPolicy(redirects))
+	if len(o.resolve) > 0 {
+		overrides := map[string][]string{}
+		for _, v := range o.resolve {
+			hostPort, addrs, err := jello.ParseResolve(v)
+			if err != nil {
+				return nil, err
+			}
+			overrides[hostPort] = addrs
+		}
+		opts = append(opts, jello.WithResolver(jello.ResolverConfig{Overrides: overrides}))
+	}
+	return opts, nil
+}
+
+// maxRedirects maps --max-redirs to RedirectPolicy.MaxRedirects, where
+// 0 means the default rather than none.
+func maxRedirects(n int) int {
+	switch {
+	case n == 0:
+		return jello.NoRedirects
+	case n < 0:
+		return math.MaxInt
+	}
+	return n
+}
+
+func run(target string, o options) error {
+	method := o.method
+	var body io.Reader
+	if o.data != "" {
+		b, err := readArg(o.data)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+		if method == "" {
+			method = http.MethodPost
+		}
+	}
+	var form *jello.RequestBody
+	if len(o.form) > 0 {
+		if o.data != "" {
+			return errors.New("-d and -F can't be used together")
+		}
+		m := jello.NewMultipart()
+		for _, arg := range o.form {
+			f, err := addFormArg(m, arg)
+			if err != nil {
+				return err
+			}
+			if f != nil {
+				defer f.Close()
+			}
+		}
+		form = m.Body()
+		if method == "" {
+			method = http.MethodPost
+		}
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	opts, err := clientOptions(o)
+	if err != nil {
+		return err
+	}
+	var jar *jello.CookieJar
+	if o.cookies != "" || o.jar != "" {
+		if jar, err = jello.NewCookieJar(nil); err != nil {
+			return err
+		}
+		if o.cookies != "" {
+			if err := jar.Load(o.cookies); err != nil {
+				return err
+			}
+		}
+		opts = append(opts, jello.WithCookieJar(jar))
+	}
+	client, err := jello.NewClient(target, opts...)
+	if err != nil {
+		return err
+	}
+	hc := *client.HTTPClient()
+	hc.Timeout = o.maxTime
+
+	var req *http.Request
+	if form != nil {
+		req, err = form.NewRequest(context.Background(), method, target)
+	} else {
+		req, err = http.NewRequest(method, target, body)
+	}
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "jcurl")
+	if o.data != "" {
+		// Same default as curl -d.
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for _, h := range o.headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return fmt.Errorf("invalid header %q, want \"Name: value\"", h)
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	res, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer res.Body.Close()
+
+	out := io.Writer(os.Stdout)
+	if o.output != "" {
+		f, err := os.Create(o.output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if o.include {
+		fmt.Fprintf(out, "%s %s\r\n", res.Proto, res.Status)
+		res.Header.Write(out)
+		fmt.Fprint(out, "\r\n")
+	}
+	n, err := io.Copy(out, res.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	res.Body.Close()
+
+	if o.jar != "" {
+		if err := jar.Save(o.jar); err != nil {
+			return err
+		}
+	}
+	if o.writeOut != "" {
+		format, err := readArg(o.writeOut)
+		if err != nil {
+			return err
+		}
+		t, _ := jello.ResponseTimings(res)
+		fmt.Fprint(os.Stdout, expandWriteOut(string(format), res, t, n))
+	}
+	return nil
+}
+
+// readArg returns v, or the contents of the file if v is "@file".
+func readArg(v string) ([]byte, error) {
+	if name, ok := strings.CutPrefix(v, "@"); ok {
+		return os.ReadFile(name)
+	}
+	return []byte(v), nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+)
+
+func TestMaxRedirects(t *testing.T) {
+	tests := []struct{ in, want int }{
+		{0, jello.NoRedirects},
+		{-1, math.MaxInt},
+		{3, 3},
+	}
+	for _, tt := range tests {
+		if got := maxRedirects(tt.in); got != tt.want {
+			t.Errorf("maxRedirects(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRunRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+		default:
+			w.Write([]byte("done"))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		opts    options
+		wantErr bool
+		want    string
+	}{
+		{name: "without -L", opts: options{}, want: "302 0 " + srv.URL + "/b"},
+		{name: "-L", opts: options{follow: true, maxRedirs: 10}, want: "200 2 "},
+		{name: "-L --max-redirs 2", opts: options{follow: true, maxRedirs: 2}, want: "200 2 "},
+		{name: "-L --max-redirs 1", opts: options{follow: true, maxRedirs: 1}, wantErr: true},
+		{name: "-L --max-redirs 0", opts: options{follow: true, maxRedirs: 0}, wantErr: true},
+		{name: "-L --max-redirs -1", opts: options{follow: true, maxRedirs: -1}, want: "200 2 "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out")
+			tt.opts.output = filepath.Join(t.TempDir(), "body")
+			tt.opts.writeOut = "%{http_code} %{num_redirects} %{redirect_url}"
+			got, err := captureStdout(t, out, func() error { return run(srv.URL+"/a", tt.opts) })
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("succeeded with %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("-w output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// captureStdout runs fn with os.Stdout sent to the file at path and
+// returns what was written.
+func captureStdout(t *testing.T, path string, fn func() error) (string, error) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = f
+	err = fn()
+	os.Stdout = stdout
+	f.Close()
+	b, _ := os.ReadFile(path)
+	return string(b), err
+}
+
+func TestExpandWriteOut(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://example.com/x", nil)
+	res := &http.Response{
+		StatusCode: 201,
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Request:    req,
+	}
+	tests := []struct{ format, want string }{
+		{"%{http_code}", "201"},
+		{"%{response_code} %{http_version}", "201 1.1"},
+		{"%{content_type}\\n", "application/json\n"},
+		{"%{url_effective}", "http://example.com/x"},
+		{"%{size_download} bytes\\t!", "42 bytes\t!"},
+		{"%{unknown} %{http_code", "%{unknown} %{http_code"},
+		{"%{num_redirects}%{redirect_url}", "0"},
+		{"100%", "100%"},
+	}
+	for _, tt := range tests {
+		if got := expandWriteOut(tt.format, res, nil, 42); got != tt.want {
+			t.Errorf("expandWriteOut(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+
+	// With timings every time variable is a number of seconds.
+	hreq, tm := jello.TimeRequest(req)
+	tm.Done()
+	res.Request = hreq
+	got := expandWriteOut("%{time_namelookup} %{time_connect} %{time_total}", res, tm, 0)
+	if fields := strings.Fields(got); len(fields) != 3 || !strings.Contains(fields[2], ".") {
+		t.Errorf("time variables = %q", got)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+)
+
+// expandWriteOut replaces the %{name} variables curl supports in -w,
+// plus the \n, \r and \t escapes. Unknown variables are left as they are.
+func expandWriteOut(format string, res *http.Response, t *jello.Timings, size int64) string {
+	seconds := func(d time.Duration) string {
+		return strconv.FormatFloat(d.Seconds(), 'f', 6, 64)
+	}
+	vars := map[string]func() string{
+		"http_code":     func() string { return strconv.Itoa(res.StatusCode) },
+		"response_code": func() string { return strconv.Itoa(res.StatusCode) },
+		"http_version":  func() string { return strings.TrimPrefix(res.Proto, "HTTP/") },
+		"content_type":  func() string { return res.Header.Get("Content-Type") },
+		"url_effective": func() string { return res.Request.URL.String() },
+		"size_download": func() string { return strconv.FormatInt(size, 10) },
+		"num_redirects": func() string { return strconv.Itoa(len(jello.RedirectChain(res)) - 1) },
+		"redirect_url": func() string {
+			// Only set when a redirect wasn't followed, as in curl.
+			if loc, err := res.Location(); err == nil && res.StatusCode/100 == 3 {
+				return loc.String()
+			}
+			return ""
+		},
+	}
+	if t != nil {
+		vars["time_namelookup"] = func() string { return seconds(t.NameLookupDone()) }
+		vars["time_connect"] = func() string { return seconds(t.ConnectDone()) }
+		vars["time_appconnect"] = func() string { return seconds(t.AppConnectDone()) }
+		vars["time_pretransfer"] = func() string { return seconds(t.PreTransferDone()) }
+		vars["time_starttransfer"] = func() string { return seconds(t.TimeToFirstByte()) }
+		vars["time_total"] = func() string { return seconds(t.Total()) }
+		vars["speed_download"] = func() string {
+			if t.Total() <= 0 {
+				return "0"
+			}
+			return strconv.FormatInt(int64(float64(size)/t.Total().Seconds()), 10)
+		}
+		host, port, _ := net.SplitHostPort(t.RemoteAddr())
+		vars["remote_ip"] = func() string { return host }
+		vars["remote_port"] = func() string { return port }
+		vars["num_connects"] = func() string {
+			if t.Reused() {
+				return "0"
+			}
+			return "1"
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		switch {
+		case strings.HasPrefix(format[i:], "%{"):
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				b.WriteString(format[i:])
+				return b.String()
+			}
+			name := format[i+2 : i+end]
+			if v, ok := vars[name]; ok {
+				b.WriteString(v())
+			} else {
+				b.WriteString(format[i : i+end+1])
+			}
+			i += end
+		case format[i] == '\\' && i+1 < len(format):
+			switch format[i+1] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(format[i+1])
+			}
+			i++
+		default:
+			b.WriteByte(format[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+	"github.com/JavierLU90/http_clients_go/router"
+)
+
+// maxBodySize caps how much of a request body is read.
+const maxBodySize = 1 << 20
+
+// resource describes one collection of the store, and how its items
+// are identified and checked.
+type resource[T any] struct {
+	name  string
+	items func(*data) *[]T
+	id    func(T) string
+	// newId assigns a fresh id to an item being created.
+	newId func(*data, *T)
+	// setId sets the id from the path on an item being replaced.
+	setId func(*T, string) error
+	// parseId rejects malformed ids in the path and returns the form
+	// the store keeps them in, e.g. "7" for "007". nil accepts any id
+	// as it is.
+	parseId func(string) (string, error)
+	// validate checks an item from a request body.
+	validate func(T) error
+}
+
+// register adds the list, get, create, update and delete routes for
+// res to r.
+func register[T any](r *router.Router, s *store, res resource[T]) {
+	base := "/" + res.name
+	r.HandleFunc(http.MethodGet, base, func(w http.ResponseWriter, req *http.Request) {
+		s.mu.RLock()
+		items := slices.Clone(*res.items(&s.data))
+		s.mu.RUnlock()
+		page, err := paginate(w, req, items)
+		if err != nil {
+			router.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		router.WriteJSON(w, http.StatusOK, page)
+	})
+
+	r.HandleFunc(http.MethodPost, base, func(w http.ResponseWriter, req *http.Request) {
+		item, ok := decode[T](w, req, res.validate)
+		if !ok {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		res.newId(&s.data, &item)
+		items := res.items(&s.data)
+		*items = append(*items, item)
+		if err := s.save(); err != nil {
+			*items = (*items)[:len(*items)-1]
+			router.WriteError(w, http.StatusInternalServerError, "error saving: "+err.Error())
+			return
+		}
+		router.WriteJSON(w, http.StatusCreated, item)
+	})
+
+	r.HandleFunc(http.MethodGet, base+"/{id}", func(w http.ResponseWriter, req *http.Request) {
+		id, ok := pathId(w, req, res.parseId)
+		if !ok {
+			return
+		}
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		items := *res.items(&s.data)
+		i := slices.IndexFunc(items, func(item T) bool { return res.id(item) == id })
+		if i < 0 {
+			router.WriteError(w, http.StatusNotFound, res.name+" "+id+" not found")
+			return
+		}
+		router.WriteJSON(w, http.StatusOK, items[i])
+	})
+
+	r.HandleFunc(http.MethodPut, base+"/{id}", func(w http.ResponseWriter, req *http.Request) {
+		id, ok := pathId(w, req, res.parseId)
+		if !ok {
+			return
+		}
+		item, ok := decode[T](w, req, res.validate)
+		if !ok {
+			return
+		}
+		if err := res.setId(&item, id); err != nil {
+			router.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		items := *res.items(&s.data)
+		i := slices.IndexFunc(items, func(item T) bool { return res.id(item) == id })
+		if i < 0 {
+			router.WriteError(w, http.StatusNotFound, res.name+" "+id+" not found")
+			return
+		}
+		old := items[i]
+		items[i] = item
+		if err := s.save(); err != nil {
+			items[i] = old
+			router.WriteError(w, http.StatusInternalServerError, "error saving: "+err.Error())
+			return
+		}
+		router.WriteJSON(w, http.StatusOK, item)
+	})
+
+	r.HandleFunc(http.MethodDelete, base+"/{id}", func(w http.ResponseWriter, req *http.Request) {
+		id, ok := pathId(w, req, res.parseId)
+		if !ok {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		items := res.items(&s.data)
+		i := slices.IndexFunc(*items, func(item T) bool { return res.id(item) == id })
+		if i < 0 {
+			router.WriteError(w, http.StatusNotFound, res.name+" "+id+" not found")
+			return
+		}
+		old := *items
+		*items = slices.Delete(slices.Clone(old), i, i+1)
+		if err := s.save(); err != nil {
+			*items = old
+			router.WriteError(w, http.StatusInternalServerError, "error saving: "+err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// paginate returns the page of items asked for with ?page= and
+// ?per_page=, and links to the next one. Without per_page every item is
+// returned.
+func paginate[T any](w http.ResponseWriter, req *http.Request, items []T) ([]T, error) {
+	q := req.URL.Query()
+	if q.Get("per_page") == "" {
+		return items, nil
+	}
+	perPage, err := strconv.Atoi(q.Get("per_page"))
+	if err != nil || perPage < 1 {
+		return nil, fmt.Errorf("per_page must be a positive number, got %q", q.Get("per_page"))
+	}
+	page := 1
+	if q.Get("page") != "" {
+		page, err = strconv.Atoi(q.Get("page"))
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("page must be a positive number, got %q", q.Get("page"))
+		}
+	}
+
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	if end < len(items) {
+		next := url.URL{Path: req.URL.Path}
+		q.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = q.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+	}
+	return items[start:end], nil
+}
+
+// pathId returns the {id} path value as parse normalizes it, writing a
+// 400 if parse rejects it.
+func pathId(w http.ResponseWriter, req *http.Request, parse func(string) (string, error)) (string, bool) {
+	id := req.PathValue("id")
+	if parse == nil {
+		return id, true
+	}
+	id, err := parse(id)
+	if err != nil {
+		router.WriteError(w, http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	return id, true
+}
+
+// decode reads a JSON item from the request body, writing a 400 if it
+// is malformed or validate rejects it.
+func decode[T any](w http.ResponseWriter, req *http.Request, validate func(T) error) (T, bool) {
+	var item T
+	dec := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxBodySize))
+	if err := dec.Decode(&item); err != nil {
+		router.WriteError(w, http.StatusBadRequest, "error decoding request body: "+err.Error())
+		return item, false
+	}
+	if err := validate(item); err != nil {
+		router.WriteError(w, http.StatusBadRequest, err.Error())
+		return item, false
+	}
+	return item, true
+}
+
+// routes registers every resource on r.
+func routes(r *router.Router, s *store) {
+	register(r, s, resource[jello.Project]{
+		name:  "projects",
+		items: func(d *data) *[]jello.Project { return &d.Projects },
+		id:    func(p jello.Project) string { return p.Id },
+		newId: func(_ *data, p *jello.Project) { p.Id = newUUID() },
+		setId: func(p *jello.Project, id string) error { p.Id = id; return nil },
+		validate: func(p jello.Project) error {
+			return required("name", p.Name)
+		},
+	})
+	register(r, s, resource[jello.Issue]{
+		name:  "issues",
+		items: func(d *data) *[]jello.Issue { return &d.Issues },
+		id:    func(i jello.Issue) string { return i.Id },
+		newId: func(_ *data, i *jello.Issue) { i.Id = newUUID() },
+		setId: func(i *jello.Issue, id string) error { i.Id = id; return nil },
+		validate: func(i jello.Issue) error {
+			if i.Estimate < 0 {
+				return fmt.Errorf("estimate must not be negative")
+			}
+			return required("title", i.Title)
+		},
+	})
+	register(r, s, resource[jello.Board]{
+		name:  "boards",
+		items: func(d *data) *[]jello.Board { return &d.Boards },
+		id:    func(b jello.Board) string { return strconv.Itoa(b.Id) },
+		newId: func(d *data, b *jello.Board) {
+			b.Id = max(d.NextBoardId, 1)
+			d.NextBoardId = b.Id + 1
+		},
+		setId: func(b *jello.Board, id string) error {
+			n, err := strconv.Atoi(id)
+			b.Id = n
+			return err
+		},
+		parseId: func(id string) (string, error) {
+			n, err := strconv.Atoi(id)
+			if err != nil {
+				return "", fmt.Errorf("board id must be a number, got %q", id)
+			}
+			return strconv.Itoa(n), nil
+		},
+		validate: func(b jello.Board) error {
+			return required("name", b.Name)
+		},
+	})
+	register(r, s, resource[jello.Comment]{
+		name:  "comments",
+		items: func(d *data) *[]jello.Comment { return &d.Comments },
+		id:    func(c jello.Comment) string { return c.Id },
+		newId: func(_ *data, c *jello.Comment) { c.Id = newUUID() },
+		setId: func(c *jello.Comment, id string) error { c.Id = id; return nil },
+		validate: func(c jello.Comment) error {
+			return required("comment", c.Comment)
+		},
+	})
+	register(r, s, resource[jello.Location]{
+		name:  "locations",
+		items: func(d *data) *[]jello.Location { return &d.Locations },
+		id:    func(l jello.Location) string { return l.Id.String() },
+		newId: func(_ *data, l *jello.Location) { l.Id = jello.LocationID(newUUID()) },
+		setId: func(l *jello.Location, id string) error { l.Id = jello.LocationID(id); return nil },
+		parseId: func(id string) (string, error) {
+			if _, err := jello.ParseLocationID(id); err != nil {
+				return "", err
+			}
+			return strings.ToLower(id), nil
+		},
+		validate: func(l jello.Location) error {
+			return required("name", l.Name)
+		},
+	})
+}
+
+func required(field, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is required", field)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Command jello-server is a small stand-in for the Jello API, so the
+// course examples and the jello client can be run locally. It serves
+// projects, issues, boards, comments and locations as JSON:
+//
+//	jello-server -addr :8080 -data jello.json -api-key secret
+//
+// and the client then uses the base URL
+// http://localhost:8080/v1/courses_rest_api/learn-http.
+//
+// Every collection answers GET and POST on /<name> and GET, PUT and
+// DELETE on /<name>/{id}. Lists are paged with ?page= and ?per_page=.
+// Data lives in memory and, with -data, is also written to a file after
+// every change and loaded from it on the next start.
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/router"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	dataFile := flag.String("data", "", "file to keep the data in (default: memory only)")
+	apiKey := flag.String("api-key", os.Getenv("JELLO_API_KEY"), "key clients must send in X-API-Key (default: $JELLO_API_KEY, none means no auth)")
+	prefix := flag.String("prefix", "/v1/courses_rest_api/learn-http", "path the API is served under")
+	flag.Parse()
+
+	s, err := openStore(*dataFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "jello-server:", err)
+		os.Exit(1)
+	}
+
+	r := router.New()
+	api := r.Group(*prefix)
+	api.Use(logRequests)
+	if *apiKey != "" {
+		api.Use(requireAPIKey(*apiKey))
+	} else {
+		log.Print("no API key set, every request is allowed")
+	}
+	routes(api, s)
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("serving on http://%s%s", *addr, *prefix)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		fmt.Fprintln(os.Stderr, "jello-server:", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	// Stop taking new connections and give requests in flight a few
+	// seconds to finish. Every change is already saved when its
+	// response is written, so there is nothing left to flush.
+	log.Print("shutting down")
+	stop()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(os.Stderr, "jello-server:", err)
+		os.Exit(1)
+	}
+}
+
+// requireAPIKey rejects requests whose X-API-Key header is not key.
+func requireAPIKey(key string) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			got := req.Header.Get("X-API-Key")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(key)) != 1 {
+				router.WriteError(w, http.StatusUnauthorized, "missing or invalid X-API-Key")
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// logRequests logs the method, path, status and duration of each request.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, req)
+		log.Printf("%s %s %d %s", req.Method, req.URL.Path, sw.status, time.Since(start).Round(time.Microsecond))
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+	"github.com/JavierLU90/http_clients_go/router"
+)
+
+const (
+	testPrefix = "/v1/courses_rest_api/learn-http"
+	testKey    = "secret"
+	bagEnd     = "52fdfc07-2182-454f-963f-5f0f9a621d72"
+)
+
+// newTestServer serves s the way main does, with testKey as the API key.
+func newTestServer(t *testing.T, s *store) *httptest.Server {
+	t.Helper()
+	r := router.New()
+	api := r.Group(testPrefix)
+	api.Use(requireAPIKey(testKey))
+	routes(api, s)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		noKey      bool
+		wantStatus int
+		wantBody   string // a substring of the response
+		wantLink   string
+	}{
+		{name: "list", method: "GET", path: "/boards", wantStatus: 200, wantBody: `"name":"Backlog"`},
+		{name: "get", method: "GET", path: "/boards/2", wantStatus: 200, wantBody: `"name":"Backlog"`},
+		{name: "leading zeros", method: "GET", path: "/boards/002", wantStatus: 200, wantBody: `"id":2,`},
+		{name: "upper-case uuid", method: "GET", path: "/locations/" + strings.ToUpper(bagEnd), wantStatus: 200, wantBody: `"name":"Bag End"`},
+		{name: "missing", method: "GET", path: "/boards/9", wantStatus: 404, wantBody: `"error":"boards 9 not found"`},
+		{name: "bad board id", method: "GET", path: "/boards/one", wantStatus: 400, wantBody: "board id must be a number"},
+		{name: "bad uuid", method: "GET", path: "/locations/bag-end", wantStatus: 400, wantBody: "not a uuid"},
+		{name: "no key", method: "GET", path: "/boards", noKey: true, wantStatus: 401, wantBody: "X-API-Key"},
+		{name: "method", method: "PATCH", path: "/boards/1", wantStatus: 405, wantBody: `"error"`},
+		{name: "no route", method: "GET", path: "/teams", wantStatus: 404, wantBody: `"error"`},
+
+		{name: "create", method: "POST", path: "/projects", body: `{"name":"New"}`, wantStatus: 201, wantBody: `"name":"New"`},
+		{name: "create invalid", method: "POST", path: "/issues", body: `{"title":"x","estimate":-1}`, wantStatus: 400, wantBody: "estimate"},
+		{name: "create missing field", method: "POST", path: "/comments", body: `{}`, wantStatus: 400, wantBody: "comment is required"},
+		{name: "create bad json", method: "POST", path: "/boards", body: `{"name":`, wantStatus: 400, wantBody: "error decoding"},
+		{name: "replace with leading zeros", method: "PUT", path: "/boards/01", body: `{"name":"Sprint 2"}`, wantStatus: 200, wantBody: `{"id":1,"name":"Sprint 2"`},
+		{name: "replace upper-case uuid", method: "PUT", path: "/locations/" + strings.ToUpper(bagEnd), body: `{"name":"Bag End"}`, wantStatus: 200, wantBody: `"id":"` + bagEnd + `"`},
+		{name: "replace missing", method: "PUT", path: "/boards/9", body: `{"name":"x"}`, wantStatus: 404},
+		{name: "delete upper-case uuid", method: "DELETE", path: "/locations/" + strings.ToUpper(bagEnd), wantStatus: 204},
+
+		{name: "first page", method: "GET", path: "/issues?per_page=2", wantStatus: 200, wantLink: `<` + testPrefix + `/issues?page=2&per_page=2>; rel="next"`},
+		{name: "last page", method: "GET", path: "/issues?per_page=2&page=2", wantStatus: 200, wantBody: "onboarding"},
+		{name: "past the end", method: "GET", path: "/issues?per_page=2&page=5", wantStatus: 200, wantBody: "[]"},
+		{name: "bad per_page", method: "GET", path: "/issues?per_page=0", wantStatus: 400, wantBody: "per_page"},
+		{name: "bad page", method: "GET", path: "/issues?per_page=1&page=x", wantStatus: 400, wantBody: "page"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := openStore("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := newTestServer(t, s)
+			req, err := http.NewRequest(tt.method, srv.URL+testPrefix+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.noKey {
+				req.Header.Set("X-API-Key", testKey)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", res.StatusCode, tt.wantStatus, body)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", body, tt.wantBody)
+			}
+			if got := res.Header.Get("Link"); got != tt.wantLink {
+				t.Errorf("Link = %q, want %q", got, tt.wantLink)
+			}
+		})
+	}
+}
+
+// TestDeleteNormalizedId checks that an item deleted through a
+// non-canonical id is really gone.
+func TestDeleteNormalizedId(t *testing.T) {
+	s, err := openStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := jello.NewClient(newTestServer(t, s).URL+testPrefix, jello.WithAPIKey(testKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := c.Locations.Delete(ctx, jello.LocationID(strings.ToUpper(bagEnd))); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Locations.Get(ctx, bagEnd)
+	var apiErr *jello.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("get after delete: error = %v, want a 404", err)
+	}
+}
+
+// TestClient runs the jello client against the server, including
+// following its pagination links.
+func TestClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jello.json")
+	s, err := openStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := jello.NewClient(newTestServer(t, s).URL+testPrefix, jello.WithAPIKey(testKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var titles []string
+	for issue, err := range c.Issues.List(ctx, &jello.ListOptions{PerPage: 1}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		titles = append(titles, issue.Title)
+	}
+	if len(titles) != 3 {
+		t.Errorf("listed issues %q, want 3", titles)
+	}
+
+	created, err := c.Locations.Create(ctx, jello.Location{Name: "Rivendell"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := created.Id.Validate(); err != nil {
+		t.Errorf("created id: %v", err)
+	}
+	if _, err := c.Locations.Update(ctx, created.Id, jello.Location{Name: "Imladris"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The changes were saved, so a new store sees them.
+	reopened, err := openStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := jello.NewClient(newTestServer(t, reopened).URL+testPrefix, jello.WithAPIKey(testKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c2.Locations.Get(ctx, created.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Imladris" {
+		t.Errorf("location after reopening = %+v", got)
+	}
+}
+
+func TestOpenStoreErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.json")
+	s, err := openStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.path = filepath.Join(t.TempDir(), "missing-dir", "jello.json")
+	s.mu.Lock()
+	err = s.save()
+	s.mu.Unlock()
+	if err == nil {
+		t.Error("saving into a missing directory succeeded")
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openStore(path); err == nil {
+		t.Error("opening a corrupt store succeeded")
+	}
+}
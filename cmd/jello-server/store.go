@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+)
+
+// data is everything the server stores, and the layout of the file it
+// is persisted to.
+type data struct {
+	Projects    []jello.Project  `json:"projects"`
+	Issues      []jello.Issue    `json:"issues"`
+	Boards      []jello.Board    `json:"boards"`
+	Comments    []jello.Comment  `json:"comments"`
+	Locations   []jello.Location `json:"locations"`
+	NextBoardId int              `json:"next_board_id"`
+}
+
+// store holds the data in memory. If path is set, every change is
+// written to it.
+type store struct {
+	mu   sync.RWMutex
+	path string
+	data data
+}
+
+// openStore loads the store from path. A missing file, or an empty
+// path, starts from the sample data.
+func openStore(path string) (*store, error) {
+	s := &store{path: path, data: sampleData()}
+	if path == "" {
+		return s, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, s.save()
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.data = data{}
+	if err := json.Unmarshal(b, &s.data); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// save writes the data to the store's file. The caller must hold the
+// lock. It writes to a temporary file first, so a crash never leaves a
+// half written store behind.
+func (s *store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(&s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// sampleData is what a new store starts with, so the course examples
+// have something to fetch.
+func sampleData() data {
+	return data{
+		Projects: []jello.Project{
+			{Id: "e9a1b5b8-4b8e-4c07-9c4e-0f4f3e6fd5a1", Name: "Website redesign"},
+			{Id: "2f7c3a1d-8e0b-4d5a-a6c2-7b9e1f3d4c5b", Name: "Mobile app"},
+		},
+		Issues: []jello.Issue{
+			{Id: "0194fdc2-fa2f-4cc0-81d3-ff12045b73c8", Title: "Fix that one bug nobody understands", Estimate: 3},
+			{Id: "4e6c0b1a-9d2f-4a8e-b5c7-3f1d2e4a6b8c", Title: "Add dark mode", Estimate: 5},
+			{Id: "7a3b9c2d-1e4f-4a5b-8c6d-9e0f1a2b3c4d", Title: "Write the onboarding docs", Estimate: 2},
+		},
+		Boards: []jello.Board{
+			{Id: 1, Name: "Sprint 1", TeamId: 1, TeamName: "Frontend"},
+			{Id: 2, Name: "Backlog", TeamId: 2, TeamName: "Backend"},
+		},
+		Comments: []jello.Comment{
+			{Id: "b3c1e2d4-5f6a-4b7c-8d9e-0a1b2c3d4e5f", UserId: "2a7f8e3c-1b4d-4e6f-9a0b-c1d2e3f4a5b6", Comment: "Looks good to me"},
+		},
+		Locations: []jello.Location{
+			{Id: "52fdfc07-2182-454f-963f-5f0f9a621d72", Name: "Bag End", Description: "A hobbit hole in the Shire"},
+		},
+		NextBoardId: 3,
+	}
+}
@@ -0,0 +1,243 @@
+// Command mirror downloads a directory tree served by the static
+// package with directory listings turned on, keeping file modification
+// times so that running it again only fetches what changed.
+//
+//	mirror -o ./docs http://localhost:8080/docs/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+	"github.com/JavierLU90/http_clients_go/static"
+)
+
+func main() {
+	out := flag.String("o", ".", "directory to mirror into")
+	workers := flag.Int("j", 4, "how many files to download at once")
+	timeout := flag.Duration("timeout", 10*time.Minute, "how long one listing or download may take, 0 for no limit")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: mirror [flags] URL")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client, err := jello.NewClient(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mirror:", err)
+		os.Exit(1)
+	}
+	m := &mirror{client: client, out: *out, timeout: *timeout}
+	if err := m.run(context.Background(), max(*workers, 1)); err != nil {
+		fmt.Fprintln(os.Stderr, "mirror:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d downloaded (%d bytes), %d unchanged\n",
+		m.downloaded.Load(), m.bytes.Load(), m.unchanged.Load())
+}
+
+type mirror struct {
+	client *jello.Client
+	out    string
+	// timeout limits each listing and download, body included. The
+	// requests go through client.HTTPClient, which the client's own
+	// timeout doesn't cover.
+	timeout time.Duration
+
+	downloaded atomic.Int64
+	unchanged  atomic.Int64
+	bytes      atomic.Int64
+}
+
+type job struct {
+	segments []string
+	entry    static.Entry
+}
+
+func (m *mirror) run(ctx context.Context, workers int) error {
+	jobs := make(chan job)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintln(os.Stderr, "mirror:", err)
+		errs = append(errs, err)
+	}
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if err := m.download(ctx, j); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	err := m.walk(ctx, nil, jobs)
+	close(jobs)
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d files failed", len(errs))
+	}
+	return nil
+}
+
+// walk lists the directory at segments and queues its files.
+func (m *mirror) walk(ctx context.Context, segments []string, jobs chan<- job) error {
+	entries, err := m.list(ctx, segments)
+	if err != nil {
+		return fmt.Errorf("error listing /%s: %w", strings.Join(segments, "/"), err)
+	}
+	if err := os.MkdirAll(filepath.Join(m.out, filepath.Join(segments...)), 0o755); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		// The names come from the server, so make sure none of them can
+		// put a file outside the output directory.
+		if !safeName(e.Name) {
+			return fmt.Errorf("server listed an unsafe name %q in /%s", e.Name, strings.Join(segments, "/"))
+		}
+		child := append(segments[:len(segments):len(segments)], e.Name)
+		if e.Dir {
+			if err := m.walk(ctx, child, jobs); err != nil {
+				return err
+			}
+			continue
+		}
+		jobs <- job{segments: child, entry: e}
+	}
+	return nil
+}
+
+// list fetches the JSON listing of the directory at segments. The URL
+// ends in a slash, the directory's own path, so the server doesn't
+// redirect to it.
+func (m *mirror) list(ctx context.Context, segments []string) ([]static.Entry, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	req, err := m.client.NewRequest().Context(ctx).Path(anys(segments)...).Build()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(req.URL.Path, "/") {
+		req.URL.Path += "/"
+		if req.URL.RawPath != "" {
+			req.URL.RawPath += "/"
+		}
+	}
+	res, err := m.client.HTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", res.Status)
+	}
+	var entries []static.Entry
+	if err := json.NewDecoder(res.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("error decoding listing: %w", err)
+	}
+	return entries, nil
+}
+
+func (m *mirror) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, m.timeout)
+}
+
+func safeName(name string) bool {
+	return name != "" && name != "." && name != ".." &&
+		!strings.ContainsAny(name, "/\\\x00") && filepath.IsLocal(name)
+}
+
+// download fetches one file unless the local copy is already current.
+func (m *mirror) download(ctx context.Context, j job) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	local := filepath.Join(m.out, filepath.Join(j.segments...))
+	req, err := m.client.NewRequest().Context(ctx).Path(anys(j.segments)...).Header("Accept", "*/*").Build()
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(local); err == nil && info.Size() == j.entry.Size {
+		req.Header.Set("If-Modified-Since", info.ModTime().UTC().Format(http.TimeFormat))
+	}
+
+	res, err := m.client.HTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading %s: %w", req.URL.Redacted(), err)
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		m.unchanged.Add(1)
+		return nil
+	case http.StatusOK:
+	default:
+		return fmt.Errorf("error downloading %s: %s", req.URL.Redacted(), res.Status)
+	}
+
+	// Write next to the target and rename, so an interrupted run never
+	// leaves a truncated file that looks current.
+	tmp, err := os.CreateTemp(filepath.Dir(local), "."+filepath.Base(local)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, res.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error downloading %s: %w", req.URL.Redacted(), err)
+	}
+	if err := os.Rename(tmp.Name(), local); err != nil {
+		return err
+	}
+
+	modTime := j.entry.ModTime
+	if t, err := http.ParseTime(res.Header.Get("Last-Modified")); err == nil {
+		modTime = t
+	}
+	if err := os.Chtimes(local, time.Time{}, modTime); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	m.downloaded.Add(1)
+	m.bytes.Add(n)
+	return nil
+}
+
+func anys(segments []string) []any {
+	out := make([]any, len(segments))
+	for i, s := range segments {
+		out[i] = s
+	}
+	return out
+}
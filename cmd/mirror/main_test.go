@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+	"github.com/JavierLU90/http_clients_go/static"
+)
+
+func newMirror(t *testing.T, base, out string) *mirror {
+	t.Helper()
+	client, err := jello.NewClient(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &mirror{client: client, out: out, timeout: 10 * time.Second}
+}
+
+func TestMirror(t *testing.T) {
+	files := map[string]string{
+		"index.txt":          "top",
+		"a b/c.txt":          "spaces",
+		"guide/intro.md":     "# intro",
+		"guide/deep/end.txt": "end",
+	}
+	src := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := static.New(src, &static.Options{ListDirectories: true, Index: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var (
+		mu        sync.Mutex
+		redirects []string
+	)
+	mux := http.NewServeMux()
+	mux.Handle("/files/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		http.StripPrefix("/files", s).ServeHTTP(rec, r)
+		if rec.Code/100 == 3 {
+			mu.Lock()
+			redirects = append(redirects, r.URL.Path)
+			mu.Unlock()
+		}
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	}))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	out := t.TempDir()
+	m := newMirror(t, srv.URL+"/files", out)
+	if err := m.run(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.downloaded.Load(); got != int64(len(files)) {
+		t.Errorf("downloaded %d files, want %d", got, len(files))
+	}
+	if len(redirects) > 0 {
+		t.Errorf("listings were redirected: %q", redirects)
+	}
+	for name, want := range files {
+		p := filepath.Join(out, filepath.FromSlash(name))
+		got, err := os.ReadFile(p)
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", name, got, err, want)
+			continue
+		}
+		srcInfo, _ := os.Stat(filepath.Join(src, filepath.FromSlash(name)))
+		info, _ := os.Stat(p)
+		if !info.ModTime().Equal(srcInfo.ModTime().Truncate(time.Second)) {
+			t.Errorf("%s mod time = %v, want %v", name, info.ModTime(), srcInfo.ModTime().Truncate(time.Second))
+		}
+	}
+
+	// A second run only revalidates.
+	again := newMirror(t, srv.URL+"/files", out)
+	if err := again.run(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if again.downloaded.Load() != 0 || again.unchanged.Load() != int64(len(files)) {
+		t.Errorf("second run: %d downloaded, %d unchanged, want 0, %d",
+			again.downloaded.Load(), again.unchanged.Load(), len(files))
+	}
+}
+
+// TestMirrorTimeout checks that a server that stops answering, before
+// the headers or in the middle of a body, can't hang a run.
+func TestMirrorTimeout(t *testing.T) {
+	tests := []struct {
+		name  string
+		stall func(w http.ResponseWriter, r *http.Request)
+	}{
+		{
+			name: "listing",
+			stall: func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+			},
+		},
+		{
+			name: "download body",
+			stall: func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/") {
+					json.NewEncoder(w).Encode([]static.Entry{{Name: "big.bin", Size: 100}})
+					return
+				}
+				w.Header().Set("Content-Length", "100")
+				w.Write([]byte("only the start"))
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(tt.stall))
+			defer srv.Close()
+			m := newMirror(t, srv.URL, t.TempDir())
+			m.timeout = 100 * time.Millisecond
+			done := make(chan error, 1)
+			go func() { done <- m.run(context.Background(), 1) }()
+			select {
+			case err := <-done:
+				if err == nil {
+					t.Error("run against a stalled server succeeded")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("run is still waiting on a stalled server")
+			}
+		})
+	}
+}
+
+func TestMirrorUnsafeNames(t *testing.T) {
+	for _, name := range []string{"..", ".", "a/b", `a\b`, "", "/etc"} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode([]static.Entry{{Name: name}})
+		}))
+		out := t.TempDir()
+		err := newMirror(t, srv.URL, out).run(context.Background(), 1)
+		srv.Close()
+		if err == nil {
+			t.Errorf("listing with %q succeeded", name)
+		}
+		if entries, _ := os.ReadDir(out); len(entries) != 0 {
+			t.Errorf("listing with %q wrote %v", name, entries)
+		}
+	}
+}
+
+func TestSafeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"a.txt", true},
+		{".hidden", true},
+		{"a b", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"a/b", false},
+		{`a\b`, false},
+		{"x\x00", false},
+	}
+	for _, tt := range tests {
+		if got := safeName(tt.name); got != tt.want {
+			t.Errorf("safeName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
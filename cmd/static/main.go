@@ -0,0 +1,43 @@
+// Command static serves a directory over HTTP using the static package.
+//
+//	static -dir ./public -addr :8080 -list
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/static"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "directory to serve")
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	list := flag.Bool("list", false, "list directories without an index file")
+	dotFiles := flag.Bool("dotfiles", false, "serve files whose names start with a dot")
+	maxAge := flag.Duration("max-age", 0, "Cache-Control max-age to send, e.g. 1h")
+	flag.Parse()
+
+	srv, err := static.New(*dir, &static.Options{
+		ListDirectories: *list,
+		ServeDotFiles:   *dotFiles,
+		MaxAge:          *maxAge,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "static:", err)
+		os.Exit(1)
+	}
+	defer srv.Close()
+
+	log.Printf("serving %s on http://%s", *dir, *addr)
+	s := &http.Server{
+		Addr:              *addr,
+		Handler:           srv,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Fatal(s.ListenAndServe())
+}
@@ -0,0 +1,112 @@
+// Command tlsinfo connects to a server and prints the certificate chain
+// and TLS parameters it presents. It exits with status 1 if there are
+// warnings, such as a chain that doesn't verify or a certificate close
+// to expiry, so monitoring scripts can check it.
+//
+//	tlsinfo api.jello.com
+//	tlsinfo -json -warn-days 14 https://boot.dev
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/tlsinfo"
+)
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	serverName := flag.String("servername", "", "server name for SNI and verification (default: the host)")
+	caFile := flag.String("cafile", "", "PEM file of CAs to verify with instead of the system roots")
+	warnDays := flag.Int("warn-days", 30, "warn about certificates expiring within this many days, 0 for only expired ones")
+	timeout := flag.Duration("timeout", 10*time.Second, "how long connecting may take")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tlsinfo [flags] HOST[:PORT]|URL")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *warnDays < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := &tlsinfo.Options{
+		ServerName: *serverName,
+		WarnWithin: warnWithin(*warnDays),
+	}
+	if *caFile != "" {
+		pem, err := os.ReadFile(*caFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "tlsinfo:", err)
+			os.Exit(1)
+		}
+		opts.RootCAs = x509.NewCertPool()
+		if !opts.RootCAs.AppendCertsFromPEM(pem) {
+			fmt.Fprintf(os.Stderr, "tlsinfo: no certificates found in %s\n", *caFile)
+			os.Exit(1)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	report, err := tlsinfo.Inspect(ctx, flag.Arg(0), opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tlsinfo:", err)
+		os.Exit(1)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		printReport(os.Stdout, report)
+	}
+	if len(report.Warnings) > 0 {
+		os.Exit(1)
+	}
+}
+
+// warnWithin converts -warn-days to Options.WarnWithin, where 0 would
+// mean the default instead of no warning.
+func warnWithin(days int) time.Duration {
+	if days == 0 {
+		return -1
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func printReport(w io.Writer, r *tlsinfo.Report) {
+	fmt.Fprintf(w, "server:   %s (%s)\n", r.ServerName, r.Addr)
+	fmt.Fprintf(w, "version:  %s\n", r.Version)
+	fmt.Fprintf(w, "cipher:   %s\n", r.CipherSuite)
+	if r.ALPN != "" {
+		fmt.Fprintf(w, "alpn:     %s\n", r.ALPN)
+	}
+	fmt.Fprintf(w, "verified: %t\n", r.Verified)
+	for i, c := range r.Chain {
+		fmt.Fprintf(w, "\ncertificate %d\n", i)
+		fmt.Fprintf(w, "  subject:   %s\n", c.Subject)
+		fmt.Fprintf(w, "  issuer:    %s\n", c.Issuer)
+		if len(c.SANs) > 0 {
+			fmt.Fprintf(w, "  sans:      %s\n", strings.Join(c.SANs, ", "))
+		}
+		fmt.Fprintf(w, "  valid:     %s to %s (%d days left)\n",
+			c.NotBefore.Format(time.DateOnly), c.NotAfter.Format(time.DateOnly), c.DaysLeft)
+		fmt.Fprintf(w, "  key:       %s\n", c.KeyType)
+		fmt.Fprintf(w, "  signature: %s\n", c.SignatureAlgorithm)
+		fmt.Fprintf(w, "  pin:       %s\n", c.SPKIHash)
+	}
+	if len(r.Warnings) > 0 {
+		fmt.Fprintln(w)
+		for _, warning := range r.Warnings {
+			fmt.Fprintf(w, "warning: %s\n", warning)
+		}
+	}
+}
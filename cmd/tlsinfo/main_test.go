@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWarnWithin(t *testing.T) {
+	tests := []struct {
+		days int
+		want time.Duration
+	}{
+		{days: 30, want: 30 * 24 * time.Hour},
+		{days: 1, want: 24 * time.Hour},
+		// 0 must not fall back to the 30 day default.
+		{days: 0, want: -1},
+	}
+	for _, tt := range tests {
+		if got := warnWithin(tt.days); got != tt.want {
+			t.Errorf("warnWithin(%d) = %v, want %v", tt.days, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package dnsinfo looks up the DNS records behind a URL: the "phonebook"
+// entries from the DNS chapter for the host url.Parse pulls out of it.
+package dnsinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/JavierLU90/http_clients_go/internal/nameserver"
+)
+
+// Resolver is the part of *net.Resolver that Lookup uses, so tests can
+// swap in their own.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupNS(ctx context.Context, name string) ([]*net.NS, error)
+}
+
+// NewResolver returns a resolver that sends every query to server,
+// e.g. "1.1.1.1:53" or a local stub, instead of the system's servers.
+func NewResolver(server string) *net.Resolver {
+	return nameserver.Resolver(server)
+}
+
+// MX is a mail server record.
+type MX struct {
+	Host string `json:"host"`
+	Pref uint16 `json:"pref"`
+}
+
+// Report is every record found for a host. Record types the host has no
+// records of are left empty; other failures are listed in Errors.
+type Report struct {
+	URL    string            `json:"url"`
+	Host   string            `json:"host"`
+	A      []string          `json:"a,omitempty"`
+	AAAA   []string          `json:"aaaa,omitempty"`
+	CNAME  string            `json:"cname,omitempty"`
+	MX     []MX              `json:"mx,omitempty"`
+	TXT    []string          `json:"txt,omitempty"`
+	NS     []string          `json:"ns,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// HostFromURL returns the host name in rawURL. A bare host name such as
+// "boot.dev" is accepted too.
+func HostFromURL(rawURL string) (string, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("error parsing url: %w", err)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return "", fmt.Errorf("url %q has no host", rawURL)
+	}
+	return host, nil
+}
+
+// Lookup reports the A, AAAA, CNAME, MX, TXT and NS records for the host
+// in rawURL. If r is nil, net.DefaultResolver is used.
+func Lookup(ctx context.Context, r Resolver, rawURL string) (*Report, error) {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	host, err := HostFromURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{URL: rawURL, Host: host}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		// Nothing to look up for an IP address.
+		if addr.Is4() {
+			report.A = []string{addr.String()}
+		} else {
+			report.AAAA = []string{addr.String()}
+		}
+		return report, nil
+	}
+
+	fail := func(typ string, err error) {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return
+		}
+		if report.Errors == nil {
+			report.Errors = map[string]string{}
+		}
+		report.Errors[typ] = err.Error()
+	}
+
+	for _, family := range []struct {
+		network string
+		typ     string
+		out     *[]string
+	}{
+		{"ip4", "A", &report.A},
+		{"ip6", "AAAA", &report.AAAA},
+	} {
+		addrs, err := r.LookupNetIP(ctx, family.network, host)
+		if err != nil {
+			fail(family.typ, err)
+			continue
+		}
+		for _, a := range addrs {
+			*family.out = append(*family.out, a.Unmap().String())
+		}
+	}
+
+	if cname, err := r.LookupCNAME(ctx, host); err != nil {
+		fail("CNAME", err)
+	} else if !strings.EqualFold(strings.TrimSuffix(cname, "."), host) {
+		report.CNAME = cname
+	}
+
+	if mxs, err := r.LookupMX(ctx, host); err != nil {
+		fail("MX", err)
+	} else {
+		for _, mx := range mxs {
+			report.MX = append(report.MX, MX{Host: mx.Host, Pref: mx.Pref})
+		}
+	}
+
+	if txts, err := r.LookupTXT(ctx, host); err != nil {
+		fail("TXT", err)
+	} else {
+		report.TXT = txts
+	}
+
+	if nss, err := r.LookupNS(ctx, host); err != nil {
+		fail("NS", err)
+	} else {
+		for _, ns := range nss {
+			report.NS = append(report.NS, ns.Host)
+		}
+		slices.Sort(report.NS)
+	}
+	return report, nil
+}
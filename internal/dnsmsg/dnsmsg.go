@@ -0,0 +1,398 @@
+// Package dnsmsg packs and unpacks DNS messages in the RFC 1035 wire
+// format, for the record types the DNS chapter talks about: A, AAAA,
+// CNAME, MX, TXT and NS. Other records are kept as raw bytes.
+package dnsmsg
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Type is a record type.
+type Type uint16
+
+const (
+	TypeA     Type = 1
+	TypeNS    Type = 2
+	TypeCNAME Type = 5
+	TypeSOA   Type = 6
+	TypeMX    Type = 15
+	TypeTXT   Type = 16
+	TypeAAAA  Type = 28
+	TypeOPT   Type = 41
+)
+
+var typeNames = map[Type]string{
+	TypeA:     "A",
+	TypeNS:    "NS",
+	TypeCNAME: "CNAME",
+	TypeSOA:   "SOA",
+	TypeMX:    "MX",
+	TypeTXT:   "TXT",
+	TypeAAAA:  "AAAA",
+	TypeOPT:   "OPT",
+}
+
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", uint16(t))
+}
+
+// ParseType returns the Type named s, e.g. "AAAA".
+func ParseType(s string) (Type, bool) {
+	for t, name := range typeNames {
+		if strings.EqualFold(name, s) {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// ClassINET is the only class used on the internet.
+const ClassINET uint16 = 1
+
+// Response codes.
+const (
+	RCodeSuccess        = 0
+	RCodeFormatError    = 1
+	RCodeServerFailure  = 2
+	RCodeNameError      = 3 // NXDOMAIN
+	RCodeNotImplemented = 4
+	RCodeRefused        = 5
+)
+
+// Header is the fixed part at the start of every message.
+type Header struct {
+	ID                 uint16
+	Response           bool
+	Opcode             uint8
+	Authoritative      bool
+	Truncated          bool
+	RecursionDesired   bool
+	RecursionAvailable bool
+	RCode              uint8
+}
+
+// Question asks for records of one type for a name.
+type Question struct {
+	Name  string
+	Type  Type
+	Class uint16
+}
+
+// Resource is a record in an answer, authority or additional section.
+// Which data fields are set depends on Type.
+type Resource struct {
+	Name  string
+	Type  Type
+	Class uint16
+	TTL   uint32
+
+	// Addr is set for A and AAAA records.
+	Addr netip.Addr
+	// Target is the name for CNAME, NS and MX records.
+	Target string
+	// Pref is the MX preference.
+	Pref uint16
+	// Text holds the strings of a TXT record.
+	Text []string
+	// Raw is the data of any other record type.
+	Raw []byte
+}
+
+// Message is a whole DNS query or response.
+type Message struct {
+	Header
+	Questions   []Question
+	Answers     []Resource
+	Authorities []Resource
+	Additionals []Resource
+}
+
+// Fqdn returns name with a trailing dot, lowercased.
+func Fqdn(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// Pack encodes m. Names are not compressed.
+func (m *Message) Pack() ([]byte, error) {
+	b := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(b[0:], m.ID)
+	var flags uint16
+	if m.Response {
+		flags |= 1 << 15
+	}
+	flags |= uint16(m.Opcode&0xf) << 11
+	if m.Authoritative {
+		flags |= 1 << 10
+	}
+	if m.Truncated {
+		flags |= 1 << 9
+	}
+	if m.RecursionDesired {
+		flags |= 1 << 8
+	}
+	if m.RecursionAvailable {
+		flags |= 1 << 7
+	}
+	flags |= uint16(m.RCode & 0xf)
+	binary.BigEndian.PutUint16(b[2:], flags)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.Questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.Answers)))
+	binary.BigEndian.PutUint16(b[8:], uint16(len(m.Authorities)))
+	binary.BigEndian.PutUint16(b[10:], uint16(len(m.Additionals)))
+
+	var err error
+	for _, q := range m.Questions {
+		if b, err = appendName(b, q.Name); err != nil {
+			return nil, err
+		}
+		b = binary.BigEndian.AppendUint16(b, uint16(q.Type))
+		b = binary.BigEndian.AppendUint16(b, q.Class)
+	}
+	for _, section := range [][]Resource{m.Answers, m.Authorities, m.Additionals} {
+		for _, r := range section {
+			if b, err = appendResource(b, r); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+func appendResource(b []byte, r Resource) ([]byte, error) {
+	b, err := appendName(b, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	b = binary.BigEndian.AppendUint16(b, uint16(r.Type))
+	b = binary.BigEndian.AppendUint16(b, r.Class)
+	b = binary.BigEndian.AppendUint32(b, r.TTL)
+
+	lengthAt := len(b)
+	b = append(b, 0, 0)
+	switch r.Type {
+	case TypeA:
+		if !r.Addr.Is4() {
+			return nil, fmt.Errorf("A record for %s needs an IPv4 address", r.Name)
+		}
+		a := r.Addr.As4()
+		b = append(b, a[:]...)
+	case TypeAAAA:
+		if !r.Addr.Is6() {
+			return nil, fmt.Errorf("AAAA record for %s needs an IPv6 address", r.Name)
+		}
+		a := r.Addr.As16()
+		b = append(b, a[:]...)
+	case TypeCNAME, TypeNS:
+		if b, err = appendName(b, r.Target); err != nil {
+			return nil, err
+		}
+	case TypeMX:
+		b = binary.BigEndian.AppendUint16(b, r.Pref)
+		if b, err = appendName(b, r.Target); err != nil {
+			return nil, err
+		}
+	case TypeTXT:
+		for _, s := range r.Text {
+			for len(s) > 255 {
+				b = append(b, 255)
+				b = append(b, s[:255]...)
+				s = s[255:]
+			}
+			b = append(b, byte(len(s)))
+			b = append(b, s...)
+		}
+	default:
+		b = append(b, r.Raw...)
+	}
+	length := len(b) - lengthAt - 2
+	if length > 0xffff {
+		return nil, fmt.Errorf("record for %s is too long", r.Name)
+	}
+	binary.BigEndian.PutUint16(b[lengthAt:], uint16(length))
+	return b, nil
+}
+
+func appendName(b []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return append(b, 0), nil
+	}
+	if len(name) > 253 {
+		return nil, fmt.Errorf("name %q is too long", name)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return nil, fmt.Errorf("invalid label in name %q", name)
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0), nil
+}
+
+var errTruncated = errors.New("dns message is truncated")
+
+// Unpack decodes a message.
+func Unpack(b []byte) (*Message, error) {
+	if len(b) < 12 {
+		return nil, errTruncated
+	}
+	flags := binary.BigEndian.Uint16(b[2:])
+	m := &Message{Header: Header{
+		ID:                 binary.BigEndian.Uint16(b[0:]),
+		Response:           flags&(1<<15) != 0,
+		Opcode:             uint8(flags>>11) & 0xf,
+		Authoritative:      flags&(1<<10) != 0,
+		Truncated:          flags&(1<<9) != 0,
+		RecursionDesired:   flags&(1<<8) != 0,
+		RecursionAvailable: flags&(1<<7) != 0,
+		RCode:              uint8(flags & 0xf),
+	}}
+	counts := [4]int{
+		int(binary.BigEndian.Uint16(b[4:])),
+		int(binary.BigEndian.Uint16(b[6:])),
+		int(binary.BigEndian.Uint16(b[8:])),
+		int(binary.BigEndian.Uint16(b[10:])),
+	}
+
+	off := 12
+	for i := 0; i < counts[0]; i++ {
+		name, n, err := readName(b, off)
+		if err != nil {
+			return nil, err
+		}
+		off = n
+		if off+4 > len(b) {
+			return nil, errTruncated
+		}
+		m.Questions = append(m.Questions, Question{
+			Name:  name,
+			Type:  Type(binary.BigEndian.Uint16(b[off:])),
+			Class: binary.BigEndian.Uint16(b[off+2:]),
+		})
+		off += 4
+	}
+	sections := []*[]Resource{&m.Answers, &m.Authorities, &m.Additionals}
+	for s, section := range sections {
+		for i := 0; i < counts[s+1]; i++ {
+			r, n, err := readResource(b, off)
+			if err != nil {
+				return nil, err
+			}
+			off = n
+			*section = append(*section, r)
+		}
+	}
+	return m, nil
+}
+
+func readResource(b []byte, off int) (Resource, int, error) {
+	name, off, err := readName(b, off)
+	if err != nil {
+		return Resource{}, 0, err
+	}
+	if off+10 > len(b) {
+		return Resource{}, 0, errTruncated
+	}
+	r := Resource{
+		Name:  name,
+		Type:  Type(binary.BigEndian.Uint16(b[off:])),
+		Class: binary.BigEndian.Uint16(b[off+2:]),
+		TTL:   binary.BigEndian.Uint32(b[off+4:]),
+	}
+	length := int(binary.BigEndian.Uint16(b[off+8:]))
+	off += 10
+	end := off + length
+	if end > len(b) {
+		return Resource{}, 0, errTruncated
+	}
+	data := b[off:end]
+
+	switch r.Type {
+	case TypeA:
+		if length != 4 {
+			return Resource{}, 0, fmt.Errorf("A record for %s has %d bytes", name, length)
+		}
+		r.Addr = netip.AddrFrom4([4]byte(data))
+	case TypeAAAA:
+		if length != 16 {
+			return Resource{}, 0, fmt.Errorf("AAAA record for %s has %d bytes", name, length)
+		}
+		r.Addr = netip.AddrFrom16([16]byte(data))
+	case TypeCNAME, TypeNS:
+		if r.Target, _, err = readName(b, off); err != nil {
+			return Resource{}, 0, err
+		}
+	case TypeMX:
+		if length < 3 {
+			return Resource{}, 0, errTruncated
+		}
+		r.Pref = binary.BigEndian.Uint16(data)
+		if r.Target, _, err = readName(b, off+2); err != nil {
+			return Resource{}, 0, err
+		}
+	case TypeTXT:
+		for i := 0; i < len(data); {
+			n := int(data[i])
+			if i+1+n > len(data) {
+				return Resource{}, 0, errTruncated
+			}
+			r.Text = append(r.Text, string(data[i+1:i+1+n]))
+			i += 1 + n
+		}
+	default:
+		r.Raw = append([]byte(nil), data...)
+	}
+	return r, end, nil
+}
+
+// readName reads a possibly compressed name at off and returns it with
+// a trailing dot, and the offset just past it.
+func readName(b []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; ; {
+		if off >= len(b) {
+			return "", 0, errTruncated
+		}
+		c := int(b[off])
+		switch c & 0xc0 {
+		case 0x00:
+			if c == 0 {
+				if end < 0 {
+					end = off + 1
+				}
+				return strings.Join(labels, ".") + ".", end, nil
+			}
+			if off+1+c > len(b) {
+				return "", 0, errTruncated
+			}
+			labels = append(labels, string(b[off+1:off+1+c]))
+			off += 1 + c
+		case 0xc0:
+			if off+1 >= len(b) {
+				return "", 0, errTruncated
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			if jumps++; jumps > 64 {
+				return "", 0, errors.New("dns name has a compression loop")
+			}
+			off = int(binary.BigEndian.Uint16(b[off:]) & 0x3fff)
+		default:
+			return "", 0, fmt.Errorf("invalid dns label byte %#x", c)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package dnsmsg
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPackUnpack(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{
+			name: "query",
+			msg: Message{
+				Header:    Header{ID: 0xbeef, RecursionDesired: true},
+				Questions: []Question{{Name: "example.com.", Type: TypeAAAA, Class: ClassINET}},
+			},
+		},
+		{
+			name: "answers",
+			msg: Message{
+				Header:    Header{ID: 7, Response: true, Authoritative: true, RecursionDesired: true, RecursionAvailable: true},
+				Questions: []Question{{Name: "www.example.com.", Type: TypeA, Class: ClassINET}},
+				Answers: []Resource{
+					{Name: "www.example.com.", Type: TypeCNAME, Class: ClassINET, TTL: 300, Target: "example.com."},
+					{Name: "example.com.", Type: TypeA, Class: ClassINET, TTL: 60, Addr: netip.MustParseAddr("192.0.2.1")},
+					{Name: "example.com.", Type: TypeAAAA, Class: ClassINET, TTL: 60, Addr: netip.MustParseAddr("2001:db8::1")},
+				},
+			},
+		},
+		{
+			name: "mx txt ns",
+			msg: Message{
+				Header: Header{Response: true},
+				Answers: []Resource{
+					{Name: "example.com.", Type: TypeMX, Class: ClassINET, TTL: 3600, Pref: 10, Target: "mail.example.com."},
+					{Name: "example.com.", Type: TypeTXT, Class: ClassINET, TTL: 3600, Text: []string{"v=spf1 -all", "second"}},
+				},
+				Authorities: []Resource{
+					{Name: "example.com.", Type: TypeNS, Class: ClassINET, TTL: 86400, Target: "ns1.example.com."},
+				},
+			},
+		},
+		{
+			name: "nxdomain with soa",
+			msg: Message{
+				Header:    Header{ID: 1, Response: true, RCode: RCodeNameError},
+				Questions: []Question{{Name: "missing.example.com.", Type: TypeA, Class: ClassINET}},
+				Authorities: []Resource{
+					{Name: "example.com.", Type: TypeSOA, Class: ClassINET, TTL: 900, Raw: []byte{0, 0, 1, 2, 3}},
+				},
+				Additionals: []Resource{{Name: ".", Type: TypeOPT, Class: 4096}},
+			},
+		},
+		{
+			name: "long txt",
+			msg: Message{
+				Answers: []Resource{
+					{Name: "example.com.", Type: TypeTXT, Class: ClassINET, Text: []string{strings.Repeat("a", 255), "b"}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.msg.Pack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Unpack(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.msg) {
+				t.Errorf("round trip:\ngot  %+v\nwant %+v", *got, tt.msg)
+			}
+		})
+	}
+}
+
+func TestPackSplitsLongText(t *testing.T) {
+	m := Message{Answers: []Resource{
+		{Name: "example.com.", Type: TypeTXT, Class: ClassINET, Text: []string{strings.Repeat("a", 300)}},
+	}}
+	b, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Unpack(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{strings.Repeat("a", 255), strings.Repeat("a", 45)}
+	if !reflect.DeepEqual(got.Answers[0].Text, want) {
+		t.Errorf("text = %q, want %q", got.Answers[0].Text, want)
+	}
+}
+
+func TestPackErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Resource
+	}{
+		{name: "A with ipv6", r: Resource{Name: "a.", Type: TypeA, Addr: netip.MustParseAddr("::1")}},
+		{name: "AAAA with ipv4", r: Resource{Name: "a.", Type: TypeAAAA, Addr: netip.MustParseAddr("127.0.0.1")}},
+		{name: "empty label", r: Resource{Name: "a..b.", Type: TypeA, Addr: netip.MustParseAddr("127.0.0.1")}},
+		{name: "long label", r: Resource{Name: strings.Repeat("a", 64) + ".", Type: TypeNS, Target: "b."}},
+		{name: "long name", r: Resource{Name: strings.Repeat("abcdefghi.", 26), Type: TypeNS, Target: "b."}},
+	}
+	for _, tt := range tests {
+		m := Message{Answers: []Resource{tt.r}}
+		if _, err := m.Pack(); err == nil {
+			t.Errorf("%s: Pack succeeded, want error", tt.name)
+		}
+	}
+}
+
+// TestUnpackCompressed checks names that point back at earlier ones,
+// which real servers send but Pack never writes.
+func TestUnpackCompressed(t *testing.T) {
+	b := []byte{
+		0, 1, 0x81, 0x80, 0, 1, 0, 1, 0, 0, 0, 0,
+		// Question: example.com A IN, at offset 12.
+		7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0, 0, 1, 0, 1,
+		// Answer: www + pointer to offset 12, CNAME to a pointer.
+		3, 'w', 'w', 'w', 0xc0, 12, 0, 5, 0, 1, 0, 0, 0, 60, 0, 2, 0xc0, 12,
+	}
+	m, err := Unpack(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Answers[0]; got.Name != "www.example.com." || got.Target != "example.com." || got.TTL != 60 {
+		t.Errorf("answer = %+v", got)
+	}
+	if !m.Response || !m.RecursionDesired || !m.RecursionAvailable {
+		t.Errorf("header = %+v", m.Header)
+	}
+}
+
+func TestUnpackErrors(t *testing.T) {
+	valid, err := (&Message{
+		Questions: []Question{{Name: "example.com.", Type: TypeA, Class: ClassINET}},
+		Answers:   []Resource{{Name: "example.com.", Type: TypeA, Class: ClassINET, Addr: netip.MustParseAddr("192.0.2.1")}},
+	}).Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loop := make([]byte, 12, 14)
+	binary.BigEndian.PutUint16(loop[4:], 1)
+	loop = append(loop, 0xc0, 12)
+
+	badA := append([]byte(nil), valid...)
+	// Claim the A record is 5 bytes long.
+	binary.BigEndian.PutUint16(badA[len(badA)-6:], 5)
+
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{name: "short header", b: valid[:11]},
+		{name: "cut question", b: valid[:20]},
+		{name: "cut answer", b: valid[:len(valid)-2]},
+		{name: "compression loop", b: loop},
+		{name: "bad A length", b: badA},
+		{name: "bad label byte", b: append(append([]byte(nil), valid[:12]...), 0x80)},
+	}
+	for _, tt := range tests {
+		if _, err := Unpack(tt.b); err == nil {
+			t.Errorf("%s: Unpack succeeded, want error", tt.name)
+		}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	for _, typ := range []Type{TypeA, TypeNS, TypeCNAME, TypeSOA, TypeMX, TypeTXT, TypeAAAA} {
+		got, ok := ParseType(strings.ToLower(typ.String()))
+		if !ok || got != typ {
+			t.Errorf("ParseType(%q) = %v, %v", typ.String(), got, ok)
+		}
+	}
+	if _, ok := ParseType("SRV"); ok {
+		t.Error("ParseType(SRV) succeeded")
+	}
+	if got := Type(33).String(); got != "TYPE33" {
+		t.Errorf("Type(33) = %s, want TYPE33", got)
+	}
+}
+
+func TestFqdn(t *testing.T) {
+	for in, want := range map[string]string{
+		"Example.COM":  "example.com.",
+		"example.com.": "example.com.",
+		"":             ".",
+	} {
+		if got := Fqdn(in); got != want {
+			t.Errorf("Fqdn(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+// Package nameserver builds resolvers that ask one DNS server instead of
+// the ones the system is configured with.
+package nameserver
+
+import (
+	"context"
+	"net"
+)
+
+// Resolver returns a resolver that sends every query to addr, e.g.
+// "1.1.1.1:53". The pure Go resolver is used, since the cgo one can't
+// be pointed at a server.
+func Resolver(addr string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
@@ -0,0 +1,86 @@
+package jello
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxBodySize is the largest response body the client will read
+// unless WithMaxBodySize says otherwise.
+const DefaultMaxBodySize = 10 << 20
+
+// maxDrain is how much of an unread body is discarded before closing it.
+// Reading to EOF lets the connection go back to the pool; anything larger
+// is cheaper to drop along with the connection.
+const maxDrain = 64 << 10
+
+// ErrBodyTooLarge matches a BodyTooLargeError.
+var ErrBodyTooLarge = errors.New("jello: response body too large")
+
+// BodyTooLargeError is returned when a response body is bigger than the
+// client's maximum body size.
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("response body is larger than %d bytes", e.Limit)
+}
+
+// Is lets errors.Is(err, ErrBodyTooLarge) match.
+func (e *BodyTooLargeError) Is(target error) bool {
+	return target == ErrBodyTooLarge
+}
+
+// WithMaxBodySize limits how many bytes of a response body are read.
+// Reading past the limit fails with a BodyTooLargeError. 0 or less means
+// no limit.
+func WithMaxBodySize(n int64) Option {
+	return func(c *Client) {
+		c.maxBodySize = n
+	}
+}
+
+// limitedBody fails reads once more than limit bytes have been read,
+// unlike io.LimitReader which silently stops at the limit.
+type limitedBody struct {
+	io.ReadCloser
+	limit     int64
+	remaining int64
+}
+
+func newLimitedBody(body io.ReadCloser, limit int64) io.ReadCloser {
+	if limit <= 0 {
+		return body
+	}
+	return &limitedBody{ReadCloser: body, limit: limit, remaining: limit}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if b.remaining <= 0 {
+		// Only an error if there is actually more to read.
+		var probe [1]byte
+		n, err := b.ReadCloser.Read(probe[:])
+		if n > 0 {
+			return 0, &BodyTooLargeError{Limit: b.limit}
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// drainAndClose discards what is left of body, up to maxDrain bytes, and
+// closes it, so the connection can be reused.
+func drainAndClose(body io.ReadCloser) {
+	io.CopyN(io.Discard, body, maxDrain)
+	body.Close()
+}
@@ -0,0 +1,113 @@
+package jello
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type nopCloser struct{ io.Reader }
+
+func (nopCloser) Close() error { return nil }
+
+func TestLimitedBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		limit   int64
+		wantErr bool
+	}{
+		{name: "under the limit", body: "hello", limit: 10},
+		{name: "exactly the limit", body: "hello", limit: 5},
+		{name: "one byte over", body: "hello!", limit: 5, wantErr: true},
+		{name: "no limit", body: strings.Repeat("x", 1<<16), limit: 0},
+		{name: "empty", body: "", limit: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newLimitedBody(nopCloser{strings.NewReader(tt.body)}, tt.limit)
+			got, err := io.ReadAll(b)
+			if tt.wantErr {
+				var tooLarge *BodyTooLargeError
+				if !errors.As(err, &tooLarge) || tooLarge.Limit != tt.limit || !errors.Is(err, ErrBodyTooLarge) {
+					t.Fatalf("error = %v, want BodyTooLargeError{%d}", err, tt.limit)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.body {
+				t.Fatalf("read %d bytes, %v; want %d bytes", len(got), err, len(tt.body))
+			}
+		})
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	big := "[" + strings.Repeat(`{"name":"x"},`, 100) + `{"name":"x"}]`
+	tests := []struct {
+		name    string
+		chunked bool
+		limit   int64
+		wantErr bool
+	}{
+		{name: "content length over the limit", limit: 100, wantErr: true},
+		{name: "chunked body over the limit", chunked: true, limit: 100, wantErr: true},
+		{name: "under the limit", limit: 1 << 20},
+		{name: "no limit", limit: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.chunked {
+					w.(http.Flusher).Flush()
+				}
+				io.WriteString(w, big)
+			}), WithMaxBodySize(tt.limit))
+			_, err := c.Locations.List(context.Background())
+			if tt.wantErr != errors.Is(err, ErrBodyTooLarge) {
+				t.Fatalf("error = %v, want too large %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestDrainAndCloseReusesConnection(t *testing.T) {
+	body := strings.Repeat("x", 32<<10)
+	tests := []struct {
+		name      string
+		close     bool
+		wantConns int64
+	}{
+		{name: "drainAndClose", close: true, wantConns: 1},
+		{name: "left open", close: false, wantConns: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, dialed := newCountingServer(t)
+			srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, body)
+			})
+			hc := &http.Client{Transport: newTransport(DefaultTransportConfig(), nil)}
+			for range 3 {
+				res, err := hc.Get(srv.URL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				res.Body.Read(make([]byte, 10))
+				if tt.close {
+					drainAndClose(res.Body)
+				} else {
+					defer res.Body.Close()
+				}
+			}
+			if got := dialed.Load(); got != tt.wantConns {
+				t.Errorf("dialed %d connections, want %d", got, tt.wantConns)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package jello
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a circuit breaker for one host.
+type BreakerState int
+
+const (
+	// StateClosed lets requests through and counts failures.
+	StateClosed BreakerState = iota
+	// StateOpen fails requests with ErrCircuitOpen until the cooldown ends.
+	StateOpen
+	// StateHalfOpen lets a few probe requests through to decide whether
+	// to close again.
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// BreakerConfig configures a CircuitBreaker. Zero fields use the values
+// from DefaultBreakerConfig.
+type BreakerConfig struct {
+	// FailureRatio opens the circuit once this share of requests in the
+	// current window has failed.
+	FailureRatio float64
+	// MinRequests is how many requests a window needs before the ratio
+	// is checked, so one early failure doesn't open the circuit.
+	MinRequests int
+	// Window is how long failures are counted before starting over.
+	Window time.Duration
+	// Cooldown is how long the circuit stays open before probing.
+	Cooldown time.Duration
+	// HalfOpenRequests is how many probes must succeed to close again.
+	HalfOpenRequests int
+	// IsFailure decides whether a request counts as failed. By default
+	// transport errors and 5xx responses do. Requests the caller
+	// canceled say nothing about the host and are not counted at all.
+	IsFailure func(res *http.Response, err error) bool
+	// OnStateChange is called whenever a host's circuit changes state.
+	OnStateChange func(host string, from, to BreakerState)
+}
+
+// DefaultBreakerConfig returns the settings used for zero fields.
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureRatio:     0.5,
+		MinRequests:      10,
+		Window:           30 * time.Second,
+		Cooldown:         15 * time.Second,
+		HalfOpenRequests: 1,
+		IsFailure:        isFailure,
+	}
+}
+
+func isFailure(res *http.Response, err error) bool {
+	return err != nil || res.StatusCode >= 500
+}
+
+// CircuitBreaker stops sending requests to a host that keeps failing,
+// so callers get ErrCircuitOpen straight away instead of all waiting
+// for the timeout. Each host has its own circuit.
+type CircuitBreaker struct {
+	cfg BreakerConfig
+
+	mu    sync.Mutex
+	hosts map[string]*circuit
+}
+
+type circuit struct {
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+// NewCircuitBreaker returns a breaker using cfg.
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	def := DefaultBreakerConfig()
+	if cfg.FailureRatio <= 0 {
+		cfg.FailureRatio = def.FailureRatio
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = def.MinRequests
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = def.Window
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = def.Cooldown
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = def.HalfOpenRequests
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = def.IsFailure
+	}
+	return &CircuitBreaker{cfg: cfg, hosts: map[string]*circuit{}}
+}
+
+// WithCircuitBreaker fails requests fast while their host's circuit is
+// open.
+func WithCircuitBreaker(b *CircuitBreaker) Option {
+	return func(c *Client) {
+		c.breaker = b
+	}
+}
+
+// State returns the current state of host's circuit.
+func (b *CircuitBreaker) State(host string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.hosts[host]
+	if !ok {
+		return StateClosed
+	}
+	if c.state == StateOpen && time.Since(c.openedAt) >= b.cfg.Cooldown {
+		return StateHalfOpen
+	}
+	return c.state
+}
+
+// Middleware wraps next with the breaker.
+func (b *CircuitBreaker) Middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		host := req.URL.Host
+		if err := b.allow(host); err != nil {
+			return nil, err
+		}
+		res, err := next.RoundTrip(req)
+		if err != nil && errors.Is(err, context.Canceled) {
+			b.release(host)
+			return res, err
+		}
+		b.record(host, b.cfg.IsFailure(res, err))
+		return res, err
+	})
+}
+
+// allow reports whether a request to host may be sent.
+func (b *CircuitBreaker) allow(host string) error {
+	b.mu.Lock()
+	c, ok := b.hosts[host]
+	if !ok {
+		c = &circuit{windowStart: time.Now()}
+		b.hosts[host] = c
+	}
+
+	var changed func()
+	switch c.state {
+	case StateOpen:
+		retryIn := b.cfg.Cooldown - time.Since(c.openedAt)
+		if retryIn > 0 {
+			b.mu.Unlock()
+			return fmt.Errorf("%w: %s, retry in %s", ErrCircuitOpen, host, retryIn.Round(time.Millisecond))
+		}
+		changed = b.setState(host, c, StateHalfOpen)
+		fallthrough
+	case StateHalfOpen:
+		if c.probes >= b.cfg.HalfOpenRequests {
+			b.mu.Unlock()
+			notify(changed)
+			return fmt.Errorf("%w: %s, waiting for probe requests", ErrCircuitOpen, host)
+		}
+		c.probes++
+	}
+	b.mu.Unlock()
+	notify(changed)
+	return nil
+}
+
+// record counts the outcome of a request to host.
+func (b *CircuitBreaker) record(host string, failed bool) {
+	b.mu.Lock()
+	c := b.hosts[host]
+	var changed func()
+	switch c.state {
+	case StateClosed:
+		if time.Since(c.windowStart) > b.cfg.Window {
+			c.windowStart = time.Now()
+			c.requests, c.failures = 0, 0
+		}
+		c.requests++
+		if failed {
+			c.failures++
+		}
+		if c.requests >= b.cfg.MinRequests && float64(c.failures)/float64(c.requests) >= b.cfg.FailureRatio {
+			changed = b.setState(host, c, StateOpen)
+		}
+	case StateHalfOpen:
+		if failed {
+			changed = b.setState(host, c, StateOpen)
+			break
+		}
+		c.successes++
+		if c.successes >= b.cfg.HalfOpenRequests {
+			changed = b.setState(host, c, StateClosed)
+		}
+	}
+	b.mu.Unlock()
+	notify(changed)
+}
+
+// release frees the probe slot taken by a request that was canceled,
+// so the circuit stays half-open and another request can probe.
+func (b *CircuitBreaker) release(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c := b.hosts[host]; c.state == StateHalfOpen && c.probes > 0 {
+		c.probes--
+	}
+}
+
+// setState moves c to state and returns the callback to run once the
+// lock is released, or nil.
+func (b *CircuitBreaker) setState(host string, c *circuit, state BreakerState) func() {
+	from := c.state
+	c.state = state
+	c.probes, c.successes = 0, 0
+	switch state {
+	case StateOpen:
+		c.openedAt = time.Now()
+	case StateClosed:
+		c.windowStart = time.Now()
+		c.requests, c.failures = 0, 0
+	}
+	if b.cfg.OnStateChange == nil || from == state {
+		return nil
+	}
+	return func() { b.cfg.OnStateChange(host, from, state) }
+}
+
+func notify(changed func()) {
+	if changed != nil {
+		changed()
+	}
+}
@@ -0,0 +1,157 @@
+package jello
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// step sends one request through the breaker with the given outcome:
+// "ok", "500", "error" or "cancel". "wait" sleeps past the cooldown.
+type step struct {
+	do        string
+	wantOpen  bool // the breaker refuses the request
+	wantState BreakerState
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = 30 * time.Millisecond
+	tests := []struct {
+		name             string
+		halfOpenRequests int
+		steps            []step
+	}{
+		{
+			name: "stays closed below the minimum requests",
+			steps: []step{
+				{do: "500", wantState: StateClosed},
+				{do: "500", wantState: StateClosed},
+				{do: "500", wantState: StateClosed},
+			},
+		},
+		{
+			name: "opens at the failure ratio",
+			steps: []step{
+				{do: "ok", wantState: StateClosed},
+				{do: "ok", wantState: StateClosed},
+				{do: "500", wantState: StateClosed},
+				{do: "error", wantState: StateOpen},
+				{do: "ok", wantOpen: true, wantState: StateOpen},
+			},
+		},
+		{
+			name: "probe success closes",
+			steps: []step{
+				{do: "500"}, {do: "500"}, {do: "500"}, {do: "500", wantState: StateOpen},
+				{do: "wait", wantState: StateHalfOpen},
+				{do: "ok", wantState: StateClosed},
+				{do: "ok", wantState: StateClosed},
+			},
+		},
+		{
+			name: "probe failure opens again",
+			steps: []step{
+				{do: "500"}, {do: "500"}, {do: "500"}, {do: "500", wantState: StateOpen},
+				{do: "wait", wantState: StateHalfOpen},
+				{do: "500", wantState: StateOpen},
+				{do: "ok", wantOpen: true, wantState: StateOpen},
+			},
+		},
+		{
+			name: "canceled probe leaves it half-open",
+			steps: []step{
+				{do: "500"}, {do: "500"}, {do: "500"}, {do: "500", wantState: StateOpen},
+				{do: "wait", wantState: StateHalfOpen},
+				{do: "cancel", wantState: StateHalfOpen},
+				{do: "cancel", wantState: StateHalfOpen},
+				{do: "500", wantState: StateOpen},
+			},
+		},
+		{
+			name: "canceled requests are not counted while closed",
+			steps: []step{
+				{do: "cancel"}, {do: "cancel"}, {do: "cancel"}, {do: "cancel"}, {do: "cancel"},
+				{do: "500"}, {do: "500", wantState: StateClosed},
+			},
+		},
+		{
+			name:             "several probes needed",
+			halfOpenRequests: 2,
+			steps: []step{
+				{do: "500"}, {do: "500"}, {do: "500"}, {do: "500", wantState: StateOpen},
+				{do: "wait", wantState: StateHalfOpen},
+				{do: "ok", wantState: StateHalfOpen},
+				{do: "ok", wantState: StateClosed},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var changes []string
+			b := NewCircuitBreaker(BreakerConfig{
+				MinRequests:      4,
+				Cooldown:         cooldown,
+				HalfOpenRequests: tt.halfOpenRequests,
+				OnStateChange: func(host string, from, to BreakerState) {
+					changes = append(changes, fmt.Sprintf("%s:%s->%s", host, from, to))
+				},
+			})
+			rt := b.Middleware(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				switch req.Header.Get("Outcome") {
+				case "error":
+					return nil, errors.New("connection refused")
+				case "cancel":
+					return nil, context.Canceled
+				case "500":
+					return &http.Response{StatusCode: http.StatusInternalServerError, Body: http.NoBody}, nil
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}))
+
+			for i, s := range tt.steps {
+				if s.do == "wait" {
+					time.Sleep(cooldown + 10*time.Millisecond)
+				} else {
+					req := httptest.NewRequest(http.MethodGet, "http://api.test/x", nil)
+					req.Header.Set("Outcome", s.do)
+					_, err := rt.RoundTrip(req)
+					if open := errors.Is(err, ErrCircuitOpen); open != s.wantOpen {
+						t.Fatalf("step %d (%s): error = %v, want open %v", i, s.do, err, s.wantOpen)
+					}
+				}
+				if got := b.State("api.test"); got != s.wantState {
+					t.Fatalf("step %d (%s): state = %s, want %s (changes %v)", i, s.do, got, s.wantState, changes)
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerPerHost(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer healthy.Close()
+
+	b := NewCircuitBreaker(BreakerConfig{MinRequests: 2, Cooldown: time.Minute})
+	bad, _ := NewClient(failing.URL, WithCircuitBreaker(b))
+	good, _ := NewClient(healthy.URL, WithCircuitBreaker(b))
+	ctx := context.Background()
+	for range 2 {
+		bad.Locations.List(ctx)
+	}
+	if _, err := bad.Locations.List(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error = %v, want ErrCircuitOpen", err)
+	}
+	if _, err := good.Locations.List(ctx); err != nil {
+		t.Fatalf("other host: %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+// Package jello is a small client for the Jello API used in the course chapters.
+//
+// Instead of building URLs with string literals and creating a new
+// http.Client on every call, everything goes through a single Client.
+package jello
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the address of the Jello API.
+const DefaultBaseURL = "https://api.jello.com"
+
+// Client talks to a Jello-like REST API.
+type Client struct {
+	baseURL     *url.URL
+	httpClient  *http.Client
+	timeout     time.Duration
+	maxBodySize int64
+	rateLimiter *RateLimiter
+	breaker     *CircuitBreaker
+	middleware  []stagedMiddleware
+	resolver    *ResolverConfig
+	tlsConfig   *TLSConfig
+	proxy       *ProxyConfig
+	jar         http.CookieJar
+	redirects   *RedirectPolicy
+
+	transportConfig TransportConfig
+	stats           connStats
+
+	Projects  *ProjectsService
+	Issues    *IssuesService
+	Boards    *BoardsService
+	Comments  *CommentsService
+	Locations *LocationsService
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey sets the X-API-Key header sent with every request. It is
+// the same as adding APIKey at StageAuth.
+func WithAPIKey(apiKey string) Option {
+	return WithMiddleware(StageAuth, APIKey(apiKey))
+}
+
+// WithHTTPClient replaces the underlying http.Client, including its
+// transport, so WithTransportConfig has no effect. Its Timeout field
+// applies on top of the client timeout, so it is best left at 0.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// NewClient creates a client for the API at baseURL, e.g.
+// "https://api.boot.dev/v1/courses_rest_api/learn-http".
+func NewClient(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing base url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("base url must be http or https, got %q", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+
+	c := &Client{
+		baseURL:         u,
+		timeout:         DefaultTimeout,
+		maxBodySize:     DefaultMaxBodySize,
+		transportConfig: DefaultTransportConfig(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.httpClient == nil {
+		t := newTransport(c.transportConfig, c.dialer())
+		if t.Proxy, err = c.proxyFunc(); err != nil {
+			return nil, err
+		}
+		if c.tlsConfig != nil {
+			if t.TLSClientConfig, err = c.tlsConfig.build(); err != nil {
+				return nil, err
+			}
+		}
+		c.httpClient = &http.Client{Transport: t}
+		if c.redirects == nil {
+			c.redirects = &RedirectPolicy{}
+		}
+	} else {
+		// Copy it so wrapping the transport doesn't change the caller's.
+		hc := *c.httpClient
+		c.httpClient = &hc
+	}
+	if c.jar != nil {
+		c.httpClient.Jar = c.jar
+	}
+	if c.redirects != nil {
+		c.httpClient.CheckRedirect = c.redirects.checkRedirect
+	}
+	rt := c.httpClient.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	c.httpClient.Transport = c.buildChain(rt)
+
+	c.Projects = &ProjectsService{client: c}
+	c.Issues = &IssuesService{client: c}
+	c.Boards = &BoardsService{client: c}
+	c.Comments = &CommentsService{client: c}
+	c.Locations = &LocationsService{client: c}
+	return c, nil
+}
+
+// HTTPClient returns the http.Client requests are sent with, middleware
+// included, for making requests outside the Jello API. The client
+// timeout and body size limit are not applied to it.
+func (c *Client) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+// endpoint returns the base URL with the given path segments appended.
+// Every segment is escaped on its own, so a value like "a/../b" stays
+// a single segment instead of changing the path.
+func (c *Client) endpoint(segments ...string) (*url.URL, error) {
+	u := *c.baseURL
+	path := u.Path
+	rawPath := u.EscapedPath()
+	for _, s := range segments {
+		if s == "" || s == "." || s == ".." {
+			return nil, fmt.Errorf("invalid path segment %q", s)
+		}
+		path += "/" + s
+		rawPath += "/" + url.PathEscape(s)
+	}
+	u.Path = path
+	u.RawPath = rawPath
+	return &u, nil
+}
+
+// do sends a request to u. If in is not nil it is the body: a
+// *RequestBody as it is, anything else encoded as JSON. If out is not
+// nil the JSON response is decoded into it.
+func (c *Client) do(ctx context.Context, method string, u *url.URL, in, out any) error {
+	req, err := c.newRequest(ctx, method, u, in)
+	if err != nil {
+		return err
+	}
+	return c.doRequest(req, out)
+}
+
+// doRequest sends req and decodes the JSON response into out, if out is
+// not nil.
+func (c *Client) doRequest(req *http.Request, out any) error {
+	res, err := c.sendRequest(req)
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(res.Body)
+
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		if isContextErr(err) {
+			return newRequestError(req.Method, req.URL, err)
+		}
+		return fmt.Errorf("error decoding response body: %w", err)
+	}
+	return nil
+}
+
+// newRequest creates a request to u that accepts JSON, with in as the
+// body as described for do.
+func (c *Client) newRequest(ctx context.Context, method string, u *url.URL, in any) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	if err := setBody(req, in); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+// send makes the request and returns the response if it has a 2xx
+// status. The body is limited to the client's maximum body size. The
+// caller must close it, preferably with drainAndClose, which also ends
+// the timeout.
+func (c *Client) send(ctx context.Context, method string, u *url.URL, in any) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, u, in)
+	if err != nil {
+		return nil, err
+	}
+	return c.sendRequest(req)
+}
+
+// sendRequest is send for a request that has already been built.
+func (c *Client) sendRequest(req *http.Request) (*http.Response, error) {
+	ctx, cancel := c.withTimeout(req.Context())
+	ctx = httptrace.WithClientTrace(ctx, c.stats.trace())
+	req = req.WithContext(ctx)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, newRequestError(req.Method, req.URL, err)
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, ctx: ctx, cancel: cancel}
+	if res.StatusCode > 299 {
+		defer drainAndClose(res.Body)
+		return nil, newAPIError(req, res)
+	}
+	if c.maxBodySize > 0 && res.ContentLength > c.maxBodySize {
+		// Not worth draining, the connection is dropped instead.
+		res.Body.Close()
+		return nil, fmt.Errorf("error reading response: %w", &BodyTooLargeError{Limit: c.maxBodySize})
+	}
+	res.Body = newLimitedBody(res.Body, c.maxBodySize)
+	return res, nil
+}
@@ -0,0 +1,61 @@
+package jello
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestClient starts a server running h and returns a client for it.
+func newTestClient(t *testing.T, h http.Handler, opts ...Option) (*Client, *httptest.Server) {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(srv.URL+"/v1/learn-http", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, srv
+}
+
+func TestEndpoint(t *testing.T) {
+	c, err := NewClient("https://api.example.com/v1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		segments []string
+		want     string
+		wantErr  bool
+	}{
+		{segments: []string{"locations"}, want: "https://api.example.com/v1/locations"},
+		{segments: []string{"locations", "a/../b"}, want: "https://api.example.com/v1/locations/a%2F..%2Fb"},
+		{segments: []string{"issues", "a b?c"}, want: "https://api.example.com/v1/issues/a%20b%3Fc"},
+		{segments: []string{"locations", ".."}, wantErr: true},
+		{segments: []string{"locations", ""}, wantErr: true},
+	}
+	for _, tt := range tests {
+		u, err := c.endpoint(tt.segments...)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("endpoint(%q) = %s, want error", tt.segments, u)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("endpoint(%q): %v", tt.segments, err)
+			continue
+		}
+		if got := u.String(); got != tt.want {
+			t.Errorf("endpoint(%q) = %s, want %s", tt.segments, got, tt.want)
+		}
+	}
+}
+
+func TestNewClientRejectsBadBaseURL(t *testing.T) {
+	for _, base := range []string{"ftp://example.com", "example.com/v1", "http://[::1"} {
+		if _, err := NewClient(base); err == nil {
+			t.Errorf("NewClient(%q) succeeded, want error", base)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package jello
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// CookieJar is an http.CookieJar that can be saved to and loaded from a
+// JSON file, so a command line session survives restarts. Cookies are
+// stored by net/http/cookiejar, which applies the RFC 6265 rules.
+//
+// Give each client, or each user in a server, its own jar; a jar shared
+// between clients shares their cookies.
+type CookieJar struct {
+	jar *cookiejar.Jar
+
+	mu      sync.Mutex
+	cookies map[cookieKey]savedCookie
+}
+
+// cookieKey identifies a cookie the way a later Set-Cookie would
+// replace it.
+type cookieKey struct {
+	host, domain, path, name string
+}
+
+// savedCookie is a cookie as written to the JSON file. URL is where it
+// is replayed to when loading.
+type savedCookie struct {
+	URL      string        `json:"url"`
+	Name     string        `json:"name"`
+	Value    string        `json:"value"`
+	Domain   string        `json:"domain,omitempty"`
+	Path     string        `json:"path,omitempty"`
+	Expires  time.Time     `json:"expires,omitzero"`
+	Secure   bool          `json:"secure,omitempty"`
+	HttpOnly bool          `json:"http_only,omitempty"`
+	SameSite http.SameSite `json:"same_site,omitempty"`
+}
+
+// NewCookieJar returns an empty jar. psl decides which domains are
+// public suffixes that cookies can't be set for, such as "co.uk". If
+// psl is nil, golang.org/x/net/publicsuffix.List is used.
+func NewCookieJar(psl cookiejar.PublicSuffixList) (*CookieJar, error) {
+	if psl == nil {
+		psl = publicsuffix.List
+	}
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: psl})
+	if err != nil {
+		return nil, fmt.Errorf("error creating cookie jar: %w", err)
+	}
+	return &CookieJar{jar: jar, cookies: map[cookieKey]savedCookie{}}, nil
+}
+
+// WithCookieJar stores cookies from responses in jar and sends them with
+// later requests. Unlike the transport options, it also applies with
+// WithHTTPClient.
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(c *Client) {
+		c.jar = jar
+	}
+}
+
+// SetCookies implements http.CookieJar.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	now := time.Now()
+	j.mu.Lock()
+	for _, c := range cookies {
+		saved := savedCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   strings.ToLower(strings.TrimPrefix(c.Domain, ".")),
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite,
+		}
+		// Max-Age wins over Expires, and has to become a time to mean
+		// the same thing after loading.
+		switch {
+		case c.MaxAge > 0:
+			saved.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		case c.MaxAge < 0:
+			saved.Expires = time.Unix(1, 0)
+		}
+		// Replaying to the cookie's own path keeps Cookies(u) able to
+		// find it even if it was set from a different path.
+		replay := url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+		if c.Path != "" {
+			replay.Path = c.Path
+		}
+		saved.URL = replay.String()
+		j.cookies[cookieKey{u.Hostname(), saved.Domain, saved.Path, c.Name}] = saved
+	}
+	j.mu.Unlock()
+	j.jar.SetCookies(u, cookies)
+}
+
+// Cookies implements http.CookieJar.
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Save writes the jar's cookies to path as JSON, including session
+// cookies, the way curl -c does. The file is only readable by the user
+// since the cookies may be credentials.
+func (j *CookieJar) Save(path string) error {
+	j.mu.Lock()
+	var out []savedCookie
+	for key, saved := range j.cookies {
+		// The jar drops expired, deleted and rejected cookies, so only
+		// keep what it still has.
+		if !j.has(saved) {
+			delete(j.cookies, key)
+			continue
+		}
+		out = append(out, saved)
+	}
+	j.mu.Unlock()
+	slices.SortFunc(out, func(a, b savedCookie) int {
+		return cmp.Or(strings.Compare(a.URL, b.URL), strings.Compare(a.Name, b.Name))
+	})
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding cookies: %w", err)
+	}
+	// Write to a temporary file first so a crash can't leave half a jar.
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("error saving cookies: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error saving cookies: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error saving cookies: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error saving cookies: %w", err)
+	}
+	return nil
+}
+
+func (j *CookieJar) has(saved savedCookie) bool {
+	u, err := url.Parse(saved.URL)
+	if err != nil {
+		return false
+	}
+	for _, c := range j.jar.Cookies(u) {
+		if c.Name == saved.Name && c.Value == saved.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// Load adds the cookies saved in path to the jar, skipping any that have
+// expired since. A missing file is not an error, so the first run of a
+// command works without one.
+func (j *CookieJar) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error loading cookies: %w", err)
+	}
+	var saved []savedCookie
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("error decoding cookies from %s: %w", path, err)
+	}
+	now := time.Now()
+	for _, s := range saved {
+		if !s.Expires.IsZero() && s.Expires.Before(now) {
+			continue
+		}
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			return fmt.Errorf("invalid cookie url %q in %s: %w", s.URL, path, err)
+		}
+		j.SetCookies(u, []*http.Cookie{{
+			Name:     s.Name,
+			Value:    s.Value,
+			Domain:   s.Domain,
+			Path:     s.Path,
+			Expires:  s.Expires,
+			Secure:   s.Secure,
+			HttpOnly: s.HttpOnly,
+			SameSite: s.SameSite,
+		}})
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package jello
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func mustURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func cookieNames(cookies []*http.Cookie) []string {
+	var names []string
+	for _, c := range cookies {
+		names = append(names, c.Name+"="+c.Value)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestCookieJarPublicSuffix(t *testing.T) {
+	tests := []struct {
+		name   string
+		setURL string
+		cookie *http.Cookie
+		getURL string
+		want   []string
+	}{
+		{
+			name:   "host cookie",
+			setURL: "https://foo.co.uk/",
+			cookie: &http.Cookie{Name: "a", Value: "1"},
+			getURL: "https://foo.co.uk/",
+			want:   []string{"a=1"},
+		},
+		{
+			name:   "domain cookie for a registrable domain",
+			setURL: "https://www.foo.co.uk/",
+			cookie: &http.Cookie{Name: "a", Value: "1", Domain: "foo.co.uk"},
+			getURL: "https://api.foo.co.uk/",
+			want:   []string{"a=1"},
+		},
+		{
+			name:   "domain cookie for co.uk",
+			setURL: "https://foo.co.uk/",
+			cookie: &http.Cookie{Name: "a", Value: "1", Domain: "co.uk"},
+			getURL: "https://bar.co.uk/",
+		},
+		{
+			name:   "domain cookie for github.io",
+			setURL: "https://alice.github.io/",
+			cookie: &http.Cookie{Name: "a", Value: "1", Domain: "github.io"},
+			getURL: "https://mallory.github.io/",
+		},
+		{
+			name:   "domain cookie for a tld",
+			setURL: "https://example.com/",
+			cookie: &http.Cookie{Name: "a", Value: "1", Domain: "com"},
+			getURL: "https://other.com/",
+		},
+	}
+	for _, tt := range tests {
+		jar, err := NewCookieJar(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jar.SetCookies(mustURL(t, tt.setURL), []*http.Cookie{tt.cookie})
+		if got := cookieNames(jar.Cookies(mustURL(t, tt.getURL))); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: cookies = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCookieJarSaveLoad(t *testing.T) {
+	jar, err := NewCookieJar(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := func(raw string, cookies ...*http.Cookie) {
+		jar.SetCookies(mustURL(t, raw), cookies)
+	}
+	set("https://api.jello.com/login",
+		&http.Cookie{Name: "session", Value: "s1", HttpOnly: true},
+		&http.Cookie{Name: "theme", Value: "dark", MaxAge: 3600},
+		&http.Cookie{Name: "scoped", Value: "v1", Path: "/v1"},
+		&http.Cookie{Name: "secure", Value: "yes", Secure: true},
+		&http.Cookie{Name: "shared", Value: "all", Domain: "jello.com"},
+	)
+	set("https://api.jello.com/", &http.Cookie{Name: "gone", Value: "x", MaxAge: -1})
+	set("https://api.jello.com/", &http.Cookie{Name: "rejected", Value: "x", Domain: "com"})
+	set("https://api.jello.com/", &http.Cookie{Name: "replaced", Value: "old"})
+	set("https://api.jello.com/", &http.Cookie{Name: "replaced", Value: "new"})
+
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	if err := jar.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("cookie file mode = %v, want 0600", perm)
+	}
+
+	loaded, err := NewCookieJar(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{url: "https://api.jello.com/v1/boards", want: []string{"replaced=new", "scoped=v1", "secure=yes", "session=s1", "shared=all", "theme=dark"}},
+		{url: "https://api.jello.com/", want: []string{"replaced=new", "secure=yes", "session=s1", "shared=all", "theme=dark"}},
+		{url: "http://api.jello.com/", want: []string{"replaced=new", "session=s1", "shared=all", "theme=dark"}},
+		{url: "https://www.jello.com/", want: []string{"shared=all"}},
+		{url: "https://jello.org/"},
+	}
+	for _, tt := range tests {
+		u := mustURL(t, tt.url)
+		if got := cookieNames(jar.Cookies(u)); !slices.Equal(got, tt.want) {
+			t.Errorf("original jar sends %q to %s, want %q", got, tt.url, tt.want)
+		}
+		if got := cookieNames(loaded.Cookies(u)); !slices.Equal(got, tt.want) {
+			t.Errorf("loaded jar sends %q to %s, want %q", got, tt.url, tt.want)
+		}
+	}
+
+	// Saving the loaded jar gives the same file.
+	again := filepath.Join(t.TempDir(), "again.json")
+	if err := loaded.Save(again); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := os.ReadFile(path)
+	second, _ := os.ReadFile(again)
+	if string(first) != string(second) {
+		t.Errorf("saved again:\n%s\nwant\n%s", second, first)
+	}
+}
+
+func TestCookieJarLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	jar, err := NewCookieJar(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jar.Load(filepath.Join(dir, "missing.json")); err != nil {
+		t.Errorf("loading a missing file: %v", err)
+	}
+	bad := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad, []byte("{not json"), 0o600)
+	if err := jar.Load(bad); err == nil {
+		t.Error("loading invalid JSON succeeded")
+	}
+	expired := filepath.Join(dir, "expired.json")
+	os.WriteFile(expired, []byte(`[{"url":"https://api.jello.com/","name":"old","value":"x","expires":"2001-01-01T00:00:00Z"}]`), 0o600)
+	if err := jar.Load(expired); err != nil {
+		t.Fatal(err)
+	}
+	if got := jar.Cookies(mustURL(t, "https://api.jello.com/")); len(got) != 0 {
+		t.Errorf("expired cookie was loaded: %v", got)
+	}
+}
+
+// TestCookieJarClient checks that a session started by one client
+// carries over to another through a saved jar.
+func TestCookieJarClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/learn-http/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/", HttpOnly: true})
+	})
+	mux.HandleFunc("GET /v1/learn-http/locations", func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err != nil || c.Value != "abc" {
+			http.Error(w, "not logged in", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("[]"))
+	})
+	path := filepath.Join(t.TempDir(), "cookies.json")
+
+	jar, err := NewCookieJar(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, srv := newTestClient(t, mux, WithCookieJar(jar))
+	if _, err := c.Locations.List(context.Background()); err == nil {
+		t.Fatal("listing before logging in succeeded")
+	}
+	res, err := c.HTTPClient().Post(srv.URL+"/v1/learn-http/login", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if err := jar.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewCookieJar(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	c2, err := NewClient(srv.URL+"/v1/learn-http", WithCookieJar(loaded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c2.Locations.List(context.Background()); err != nil {
+		t.Errorf("listing with the loaded jar: %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package jello
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/internal/dnsmsg"
+)
+
+// dohContentType is the media type for DNS messages in RFC 8484.
+const dohContentType = "application/dns-message"
+
+// DoHResolver resolves host names with DNS-over-HTTPS (RFC 8484), for
+// networks where plain DNS is intercepted. Plug it in through
+// ResolverConfig.Resolver:
+//
+//	jello.WithResolver(jello.ResolverConfig{
+//		Resolver: &jello.DoHResolver{URL: "https://1.1.1.1/dns-query"},
+//	})
+//
+// Answers are cached for their TTL. If the DoH server can't be reached
+// or fails, the lookup falls back to Fallback, unless NoFallback is set.
+type DoHResolver struct {
+	// URL is the DoH endpoint. Using an IP address for the host avoids
+	// needing plain DNS to find the DoH server itself.
+	URL string
+	// UsePOST sends queries as POST bodies instead of ?dns= GET requests.
+	// GET responses are easier for HTTP caches to reuse.
+	UsePOST bool
+	// Client sends the DoH requests. Defaults to an http.Client with its
+	// own transport and a 5 second timeout.
+	Client *http.Client
+	// Fallback is used when DoH fails. Defaults to net.DefaultResolver.
+	Fallback HostResolver
+	// NoFallback turns the fallback off.
+	NoFallback bool
+
+	once  sync.Once
+	mu    sync.Mutex
+	cache map[dohKey]dohEntry
+}
+
+type dohKey struct {
+	host string
+	typ  dnsmsg.Type
+}
+
+type dohEntry struct {
+	addrs   []netip.Addr
+	expires time.Time
+}
+
+// errNoSuchHost is what the standard resolver says for NXDOMAIN.
+const errNoSuchHost = "no such host"
+
+// LookupNetIP looks up host's addresses. network is "ip", "ip4" or "ip6".
+func (r *DoHResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	addrs, _, err := r.lookupNetIPTTL(ctx, network, host)
+	return addrs, err
+}
+
+// lookupNetIPTTL is LookupNetIP that also returns the lowest TTL of the
+// addresses, or -1 when they came from the fallback.
+func (r *DoHResolver) lookupNetIPTTL(ctx context.Context, network, host string) ([]netip.Addr, time.Duration, error) {
+	r.once.Do(func() {
+		r.cache = map[dohKey]dohEntry{}
+		if r.Client == nil {
+			r.Client = &http.Client{
+				Transport: http.DefaultTransport.(*http.Transport).Clone(),
+				Timeout:   5 * time.Second,
+			}
+		}
+	})
+
+	var types []dnsmsg.Type
+	switch network {
+	case "ip":
+		types = []dnsmsg.Type{dnsmsg.TypeA, dnsmsg.TypeAAAA}
+	case "ip4":
+		types = []dnsmsg.Type{dnsmsg.TypeA}
+	case "ip6":
+		types = []dnsmsg.Type{dnsmsg.TypeAAAA}
+	default:
+		return nil, 0, fmt.Errorf("unsupported network %q", network)
+	}
+
+	type result struct {
+		addrs []netip.Addr
+		ttl   time.Duration
+		err   error
+	}
+	results := make([]result, len(types))
+	var wg sync.WaitGroup
+	for i, typ := range types {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addrs, ttl, err := r.lookupType(ctx, host, typ)
+			results[i] = result{addrs, ttl, err}
+		}()
+	}
+	wg.Wait()
+
+	var addrs []netip.Addr
+	var errs []error
+	ttl := time.Duration(-1)
+	notFound := 0
+	for _, res := range results {
+		addrs = append(addrs, res.addrs...)
+		if len(res.addrs) > 0 && (ttl < 0 || res.ttl < ttl) {
+			ttl = res.ttl
+		}
+		var dnsErr *net.DNSError
+		if errors.As(res.err, &dnsErr) && dnsErr.IsNotFound {
+			notFound++
+		} else if res.err != nil {
+			errs = append(errs, res.err)
+		}
+	}
+	if len(addrs) > 0 {
+		return addrs, ttl, nil
+	}
+	if len(errs) == 0 {
+		// The server answered and there really are no addresses.
+		return nil, 0, &net.DNSError{Err: errNoSuchHost, Name: host, IsNotFound: true}
+	}
+	if r.NoFallback {
+		return nil, 0, errors.Join(errs...)
+	}
+	fallback := r.Fallback
+	if fallback == nil {
+		fallback = net.DefaultResolver
+	}
+	addrs, err := fallback.LookupNetIP(ctx, network, host)
+	return addrs, -1, err
+}
+
+// lookupType looks up the records of one type, and returns how long
+// they may still be kept.
+func (r *DoHResolver) lookupType(ctx context.Context, host string, typ dnsmsg.Type) ([]netip.Addr, time.Duration, error) {
+	name := dnsmsg.Fqdn(host)
+	key := dohKey{host: name, typ: typ}
+	r.mu.Lock()
+	entry, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		if len(entry.addrs) == 0 {
+			return nil, 0, &net.DNSError{Err: errNoSuchHost, Name: host, IsNotFound: true}
+		}
+		return entry.addrs, time.Until(entry.expires), nil
+	}
+
+	res, err := r.exchange(ctx, &dnsmsg.Message{
+		// RFC 8484 asks for ID 0 so identical queries can be cached.
+		Header:    dnsmsg.Header{RecursionDesired: true},
+		Questions: []dnsmsg.Question{{Name: name, Type: typ, Class: dnsmsg.ClassINET}},
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch res.RCode {
+	case dnsmsg.RCodeSuccess, dnsmsg.RCodeNameError:
+	default:
+		return nil, 0, fmt.Errorf("doh lookup of %s failed with rcode %d", host, res.RCode)
+	}
+	addrs, ttl := answerAddrs(res, name, typ)
+	if ttl > 0 {
+		r.mu.Lock()
+		r.cache[key] = dohEntry{addrs: addrs, expires: time.Now().Add(ttl)}
+		r.mu.Unlock()
+	}
+	if len(addrs) == 0 {
+		return nil, ttl, &net.DNSError{Err: errNoSuchHost, Name: host, IsNotFound: true}
+	}
+	return addrs, ttl, nil
+}
+
+// answerAddrs follows the CNAME chain for name in res and returns the
+// addresses at the end of it, with the lowest TTL seen. For an empty
+// answer the TTL comes from the SOA record, as negative caching does.
+func answerAddrs(res *dnsmsg.Message, name string, typ dnsmsg.Type) ([]netip.Addr, time.Duration) {
+	var addrs []netip.Addr
+	var ttl uint32
+	seen := false
+	lower := func(t uint32) {
+		if !seen || t < ttl {
+			ttl, seen = t, true
+		}
+	}
+	for range 8 {
+		next := ""
+		for _, a := range res.Answers {
+			if dnsmsg.Fqdn(a.Name) != name {
+				continue
+			}
+			switch a.Type {
+			case typ:
+				addrs = append(addrs, a.Addr)
+				lower(a.TTL)
+			case dnsmsg.TypeCNAME:
+				next = dnsmsg.Fqdn(a.Target)
+				lower(a.TTL)
+			}
+		}
+		if len(addrs) > 0 || next == "" {
+			break
+		}
+		name = next
+	}
+	if len(addrs) == 0 {
+		seen = false
+		for _, a := range res.Authorities {
+			if a.Type == dnsmsg.TypeSOA {
+				lower(a.TTL)
+			}
+		}
+	}
+	return addrs, time.Duration(ttl) * time.Second
+}
+
+// exchange sends q to the DoH server and returns its answer.
+func (r *DoHResolver) exchange(ctx context.Context, q *dnsmsg.Message) (*dnsmsg.Message, error) {
+	wire, err := q.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	var req *http.Request
+	if r.UsePOST {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(wire))
+		if err == nil {
+			req.Header.Set("Content-Type", dohContentType)
+		}
+	} else {
+		var u *url.URL
+		u, err = url.Parse(r.URL)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing doh url: %w", err)
+		}
+		u = withQuery(u, func(v url.Values) {
+			v.Set("dns", base64.RawURLEncoding.EncodeToString(wire))
+		})
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating doh request: %w", err)
+	}
+	req.Header.Set("Accept", dohContentType)
+
+	res, err := r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making doh request: %w", err)
+	}
+	defer drainAndClose(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("doh server returned %s", res.Status)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != dohContentType {
+		return nil, fmt.Errorf("doh server returned content type %q", ct)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("error reading doh response: %w", err)
+	}
+	return dnsmsg.Unpack(body)
+}
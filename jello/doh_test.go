@@ -0,0 +1,200 @@
+package jello_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+	"github.com/JavierLU90/http_clients_go/jello/jellotest"
+)
+
+// newDoH starts a DoH stub in front of a fake DNS server with a few
+// records, and returns both with the HTTP methods the stub was sent.
+func newDoH(t *testing.T) (*jellotest.DNSServer, string, *http.Client, func() []string) {
+	t.Helper()
+	dns := jellotest.NewDNSServer(t)
+	for _, r := range []struct{ name, typ, value string }{
+		{"api.jello.test", "A", "192.0.2.1"},
+		{"api.jello.test", "AAAA", "2001:db8::1"},
+		{"v4.jello.test", "A", "192.0.2.2"},
+		{"www.jello.test", "CNAME", "api.jello.test"},
+	} {
+		if err := dns.Add(r.name, r.typ, r.value, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := jellotest.NewDoHServer(t, dns)
+
+	var mu sync.Mutex
+	var methods []string
+	next := srv.Config.Handler
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+	return dns, srv.URL + "/dns-query", srv.Client(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(methods)
+	}
+}
+
+func sortedAddrs(addrs []netip.Addr) []string {
+	var s []string
+	for _, a := range addrs {
+		s = append(s, a.String())
+	}
+	slices.Sort(s)
+	return s
+}
+
+func TestDoHResolver(t *testing.T) {
+	tests := []struct {
+		network string
+		host    string
+		post    bool
+		want    []string
+		wantErr bool
+	}{
+		{network: "ip", host: "api.jello.test", want: []string{"192.0.2.1", "2001:db8::1"}},
+		{network: "ip", host: "api.jello.test", post: true, want: []string{"192.0.2.1", "2001:db8::1"}},
+		{network: "ip4", host: "api.jello.test", want: []string{"192.0.2.1"}},
+		{network: "ip6", host: "API.jello.test", post: true, want: []string{"2001:db8::1"}},
+		{network: "ip", host: "www.jello.test", want: []string{"192.0.2.1", "2001:db8::1"}},
+		{network: "ip", host: "v4.jello.test", want: []string{"192.0.2.2"}},
+		{network: "ip6", host: "v4.jello.test", wantErr: true},
+		{network: "ip", host: "missing.jello.test", wantErr: true},
+		{network: "tcp", host: "api.jello.test", wantErr: true},
+	}
+	for _, tt := range tests {
+		_, url, client, methods := newDoH(t)
+		r := &jello.DoHResolver{URL: url, UsePOST: tt.post, Client: client, NoFallback: true}
+		addrs, err := r.LookupNetIP(context.Background(), tt.network, tt.host)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s %s: got %v, want error", tt.network, tt.host, addrs)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.network, tt.host, err)
+			continue
+		}
+		if got := sortedAddrs(addrs); !slices.Equal(got, tt.want) {
+			t.Errorf("%s %s = %v, want %v", tt.network, tt.host, got, tt.want)
+		}
+		wantMethod := http.MethodGet
+		if tt.post {
+			wantMethod = http.MethodPost
+		}
+		for _, m := range methods() {
+			if m != wantMethod {
+				t.Errorf("%s %s: sent %s, want %s", tt.network, tt.host, m, wantMethod)
+			}
+		}
+	}
+}
+
+func TestDoHResolverNotFound(t *testing.T) {
+	_, url, client, _ := newDoH(t)
+	r := &jello.DoHResolver{URL: url, Client: client}
+	_, err := r.LookupNetIP(context.Background(), "ip", "missing.jello.test")
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		t.Errorf("error = %v, want a not found DNSError", err)
+	}
+}
+
+func TestDoHResolverCache(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		negativeTTL time.Duration
+		wantCached  bool
+	}{
+		{name: "answer", host: "api.jello.test", wantCached: true},
+		{name: "cname", host: "www.jello.test", wantCached: true},
+		{name: "nxdomain with soa", host: "missing.jello.test", negativeTTL: time.Minute, wantCached: true},
+		{name: "nxdomain without soa", host: "missing.jello.test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dns, url, client, _ := newDoH(t)
+			dns.SetNegativeTTL(tt.negativeTTL)
+			r := &jello.DoHResolver{URL: url, Client: client, NoFallback: true}
+
+			first, firstErr := r.LookupNetIP(context.Background(), "ip4", tt.host)
+			queries := dns.Queries()
+			second, secondErr := r.LookupNetIP(context.Background(), "ip4", tt.host)
+			if cached := dns.Queries() == queries; cached != tt.wantCached {
+				t.Errorf("cached = %v, want %v", cached, tt.wantCached)
+			}
+			if !slices.Equal(first, second) || (firstErr == nil) != (secondErr == nil) {
+				t.Errorf("second lookup = %v, %v, first = %v, %v", second, secondErr, first, firstErr)
+			}
+		})
+	}
+}
+
+// staticResolver answers every lookup with addrs.
+type staticResolver []netip.Addr
+
+func (r staticResolver) LookupNetIP(context.Context, string, string) ([]netip.Addr, error) {
+	return r, nil
+}
+
+func TestDoHResolverFallback(t *testing.T) {
+	failing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	})
+	wrongType := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+	})
+	fallback := staticResolver{netip.MustParseAddr("192.0.2.9")}
+
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		noFallback bool
+		want       []string
+	}{
+		{name: "server error", handler: failing, want: []string{"192.0.2.9"}},
+		{name: "wrong content type", handler: wrongType, want: []string{"192.0.2.9"}},
+		{name: "no fallback", handler: failing, noFallback: true},
+		{name: "working server", want: []string{"192.0.2.1", "2001:db8::1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, url, client, _ := newDoH(t)
+			if tt.handler != nil {
+				srv := httptest.NewTLSServer(tt.handler)
+				defer srv.Close()
+				url, client = srv.URL+"/dns-query", srv.Client()
+			}
+			r := &jello.DoHResolver{URL: url, Client: client, Fallback: fallback, NoFallback: tt.noFallback}
+			addrs, err := r.LookupNetIP(context.Background(), "ip", "api.jello.test")
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("got %v, want error", addrs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sortedAddrs(addrs); !slices.Equal(got, tt.want) {
+				t.Errorf("addrs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package jello
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+var (
+	// ErrInvalidID is returned when an identifier is rejected before
+	// any request is sent.
+	ErrInvalidID = errors.New("jello: invalid id")
+
+	// ErrNotFound matches an APIError with status 404.
+	ErrNotFound = errors.New("jello: not found")
+
+	// ErrTimeout matches a RequestError caused by a deadline: the context
+	// deadline, the client timeout or a per-call timeout.
+	ErrTimeout = errors.New("jello: request timed out")
+
+	// ErrCanceled matches a RequestError caused by the caller canceling
+	// the context.
+	ErrCanceled = errors.New("jello: request canceled")
+
+	// ErrCircuitOpen is returned without sending the request when the
+	// circuit breaker for its host is open.
+	ErrCircuitOpen = errors.New("jello: circuit open")
+)
+
+// RequestError is a request that failed before a response was received,
+// or whose body could not be read in time.
+type RequestError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func newRequestError(method string, u *url.URL, err error) *RequestError {
+	// url.Error repeats the method and URL, so keep only what it wraps.
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	err = certificateError(u.Hostname(), err)
+	return &RequestError{Method: method, URL: u.Redacted(), Err: err}
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("error making request: %s %s: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the request ran out of time.
+func (e *RequestError) Timeout() bool {
+	return isTimeout(e.Err)
+}
+
+// Canceled reports whether the caller canceled the request.
+func (e *RequestError) Canceled() bool {
+	return isCanceled(e.Err)
+}
+
+// Is lets errors.Is match ErrTimeout and ErrCanceled.
+func (e *RequestError) Is(target error) bool {
+	switch target {
+	case ErrTimeout:
+		return e.Timeout()
+	case ErrCanceled:
+		return e.Canceled()
+	}
+	return false
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isCanceled(err error) bool {
+	return !isTimeout(err) && errors.Is(err, context.Canceled)
+}
+
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// APIError is a non-2xx response from the server.
+type APIError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	URL        string
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Method, e.URL, e.Status)
+}
+
+// Is lets errors.Is(err, ErrNotFound) match a 404 response.
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// maxErrorBody caps how much of an error response is kept.
+const maxErrorBody = 4 << 10
+
+func newAPIError(req *http.Request, res *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	return &APIError{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Method:     req.Method,
+		URL:        req.URL.Redacted(),
+		Body:       body,
+	}
+}
@@ -0,0 +1,287 @@
+package jello
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// RequestBody is an encoded request body and its content type. Pass one
+// where a service takes a body to send it as is instead of as JSON.
+type RequestBody struct {
+	ContentType string
+	// Length is the size in bytes, or -1 if it isn't known and the body
+	// is sent chunked.
+	Length int64
+	// open returns the body from the start. It is nil after the first
+	// call if the body can't be read again.
+	open func() (io.ReadCloser, error)
+	// replayable reports whether open can be called more than once, so
+	// retries and 307 redirects can send the body again.
+	replayable bool
+}
+
+// JSONBody encodes v as an application/json body.
+func JSONBody(v any) (*RequestBody, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding request body: %w", err)
+	}
+	return bytesBody("application/json", data), nil
+}
+
+// FormBody encodes values as an application/x-www-form-urlencoded body,
+// the format of curl -d "param1=value1&param2=value2".
+func FormBody(values url.Values) *RequestBody {
+	return bytesBody("application/x-www-form-urlencoded", []byte(values.Encode()))
+}
+
+func bytesBody(contentType string, data []byte) *RequestBody {
+	return &RequestBody{
+		ContentType: contentType,
+		Length:      int64(len(data)),
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		},
+		replayable: true,
+	}
+}
+
+// NewRequest returns a request that sends b.
+func (b *RequestBody) NewRequest(ctx context.Context, method, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	if err := b.apply(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// apply sets b as the body of req.
+func (b *RequestBody) apply(req *http.Request) error {
+	if b.open == nil {
+		return fmt.Errorf("request body has already been sent")
+	}
+	body, err := b.open()
+	if err != nil {
+		return err
+	}
+	if !b.replayable {
+		b.open = nil
+	} else {
+		req.GetBody = b.open
+	}
+	req.Body = body
+	req.ContentLength = b.Length
+	if b.Length == 0 {
+		req.Body = http.NoBody
+	}
+	req.Header.Set("Content-Type", b.ContentType)
+	return nil
+}
+
+// setBody sets in as the body of req: a *RequestBody as it is, anything
+// else encoded as JSON.
+func setBody(req *http.Request, in any) error {
+	if in == nil {
+		return nil
+	}
+	b, ok := in.(*RequestBody)
+	if !ok {
+		var err error
+		if b, err = JSONBody(in); err != nil {
+			return err
+		}
+	}
+	return b.apply(req)
+}
+
+// Multipart builds a multipart/form-data body, the format of curl -F.
+// File parts are streamed from their readers while the request is sent,
+// so whole files are never held in memory:
+//
+//	f, err := os.Open("screenshot.png")
+//	...
+//	body := jello.NewMultipart().
+//		Field("comment", "the board doesn't load").
+//		File("attachment", "screenshot.png", f).
+//		Body()
+type Multipart struct {
+	boundary string
+	parts    []formPart
+}
+
+type formPart struct {
+	name, value string
+	// filename and r are set for file parts.
+	filename    string
+	contentType string
+	r           io.Reader
+}
+
+// NewMultipart returns an empty multipart body with a random boundary.
+func NewMultipart() *Multipart {
+	var buf [16]byte
+	rand.Read(buf[:])
+	return &Multipart{boundary: fmt.Sprintf("jello-%x", buf)}
+}
+
+// Field adds a plain text field.
+func (m *Multipart) Field(name, value string) *Multipart {
+	m.parts = append(m.parts, formPart{name: name, value: value})
+	return m
+}
+
+// File adds a file part read from r, with a content type guessed from
+// the filename's extension.
+func (m *Multipart) File(name, filename string, r io.Reader) *Multipart {
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return m.FileType(name, filename, contentType, r)
+}
+
+// FileType adds a file part read from r with the given content type.
+func (m *Multipart) FileType(name, filename, contentType string, r io.Reader) *Multipart {
+	m.parts = append(m.parts, formPart{name: name, filename: filename, contentType: contentType, r: r})
+	return m
+}
+
+// Body returns the encoded body. If every file reader is an io.Seeker
+// the body can be sent again, rewound to where the readers are now;
+// otherwise it can be sent once. The length is known when every file's
+// size is: bytes.Reader, strings.Reader and *os.File all work.
+func (m *Multipart) Body() *RequestBody {
+	b := &RequestBody{
+		ContentType: "multipart/form-data; boundary=" + m.boundary,
+		Length:      m.length(),
+		replayable:  true,
+	}
+	offsets := make([]int64, len(m.parts))
+	for i, p := range m.parts {
+		if p.r == nil {
+			continue
+		}
+		s, ok := p.r.(io.Seeker)
+		if !ok {
+			b.replayable = false
+			break
+		}
+		off, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			b.replayable = false
+			break
+		}
+		offsets[i] = off
+	}
+
+	b.open = func() (io.ReadCloser, error) {
+		if b.replayable {
+			for i, p := range m.parts {
+				if p.r != nil {
+					if _, err := p.r.(io.Seeker).Seek(offsets[i], io.SeekStart); err != nil {
+						return nil, fmt.Errorf("error rewinding %s: %w", p.filename, err)
+					}
+				}
+			}
+		}
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(m.write(pw, true))
+		}()
+		return pr, nil
+	}
+	return b
+}
+
+// write encodes the parts to w. Without contents, file data is left
+// out, which length uses to measure everything else.
+func (m *Multipart) write(w io.Writer, contents bool) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(m.boundary); err != nil {
+		return err
+	}
+	for _, p := range m.parts {
+		if p.r == nil {
+			if err := mw.WriteField(p.name, p.value); err != nil {
+				return err
+			}
+			continue
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+			"name":     p.name,
+			"filename": p.filename,
+		}))
+		h.Set("Content-Type", p.contentType)
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if contents {
+			if _, err := io.Copy(part, p.r); err != nil {
+				return fmt.Errorf("error reading %s: %w", p.filename, err)
+			}
+		}
+	}
+	return mw.Close()
+}
+
+// length returns the encoded size, or -1 if a file's size is unknown.
+func (m *Multipart) length() int64 {
+	var files int64
+	for _, p := range m.parts {
+		if p.r == nil {
+			continue
+		}
+		n := readerLen(p.r)
+		if n < 0 {
+			return -1
+		}
+		files += n
+	}
+	var c countingWriter
+	if err := m.write(&c, false); err != nil {
+		return -1
+	}
+	return int64(c) + files
+}
+
+// readerLen returns how much is left to read from r, or -1.
+func readerLen(r io.Reader) int64 {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		off, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - off
+	}
+	return -1
+}
+
+type countingWriter int64
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,289 @@
+package jello
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// formPartGot is a part as the test server decoded it.
+type formPartGot struct {
+	Name, Filename, ContentType, Data string
+}
+
+// readParts decodes a multipart/form-data request body.
+func readParts(r *http.Request) ([]formPartGot, error) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	if mediaType != "multipart/form-data" {
+		return nil, errors.New("content type is " + mediaType)
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	var parts []formPartGot
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(p)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, formPartGot{p.FormName(), p.FileName(), p.Header.Get("Content-Type"), string(data)})
+	}
+}
+
+func TestFormBody(t *testing.T) {
+	var got url.Values
+	var contentType string
+	c, srv := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		r.ParseForm()
+		got = r.PostForm
+	}))
+	want := url.Values{"param1": {"value1"}, "param2": {"a b&c=d"}}
+	if err := c.do(context.Background(), http.MethodPost, mustURL(t, srv.URL), FormBody(want), nil); err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("content type = %q", contentType)
+	}
+	if got.Encode() != want.Encode() {
+		t.Errorf("form = %v, want %v", got, want)
+	}
+}
+
+func TestMultipart(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(file, []byte("from a file"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		build      func(m *Multipart) *Multipart
+		want       []formPartGot
+		wantLength bool
+	}{
+		{
+			name:       "fields",
+			build:      func(m *Multipart) *Multipart { return m.Field("a", "1").Field("b", "two words") },
+			want:       []formPartGot{{Name: "a", Data: "1"}, {Name: "b", Data: "two words"}},
+			wantLength: true,
+		},
+		{
+			name: "bytes reader",
+			build: func(m *Multipart) *Multipart {
+				return m.Field("comment", "hi").File("attachment", "shot.png", bytes.NewReader([]byte("\x89PNG")))
+			},
+			want: []formPartGot{
+				{Name: "comment", Data: "hi"},
+				{Name: "attachment", Filename: "shot.png", ContentType: "image/png", Data: "\x89PNG"},
+			},
+			wantLength: true,
+		},
+		{
+			name: "os file",
+			build: func(m *Multipart) *Multipart {
+				f, err := os.Open(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { f.Close() })
+				return m.File("attachment", "notes.txt", f)
+			},
+			want:       []formPartGot{{Name: "attachment", Filename: "notes.txt", ContentType: "text/plain; charset=utf-8", Data: "from a file"}},
+			wantLength: true,
+		},
+		{
+			name: "unknown size is chunked",
+			build: func(m *Multipart) *Multipart {
+				return m.FileType("attachment", "data", "application/x-custom", io.MultiReader(strings.NewReader("abc")))
+			},
+			want: []formPartGot{{Name: "attachment", Filename: "data", ContentType: "application/x-custom", Data: "abc"}},
+		},
+		{
+			name: "unknown extension",
+			build: func(m *Multipart) *Multipart {
+				return m.File("attachment", `we"ird.zzz`, strings.NewReader("x"))
+			},
+			want:       []formPartGot{{Name: "attachment", Filename: `we"ird.zzz`, ContentType: "application/octet-stream", Data: "x"}},
+			wantLength: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []formPartGot
+			var length int64
+			var encoding []string
+			c, srv := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				length, encoding = r.ContentLength, r.TransferEncoding
+				var err error
+				if got, err = readParts(r); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				}
+			}))
+			body := tt.build(NewMultipart()).Body()
+			if err := c.do(context.Background(), http.MethodPost, mustURL(t, srv.URL), body, nil); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parts = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("part %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+			if tt.wantLength {
+				if body.Length < 0 || length != body.Length {
+					t.Errorf("server got length %d, body length %d", length, body.Length)
+				}
+			} else if body.Length != -1 || len(encoding) != 1 || encoding[0] != "chunked" {
+				t.Errorf("body length %d, transfer encoding %q, want -1 and chunked", body.Length, encoding)
+			}
+		})
+	}
+}
+
+// TestMultipartStreams checks that a file part reaches the server while
+// its reader is still being written, so the body isn't buffered.
+func TestMultipartStreams(t *testing.T) {
+	pr, pw := io.Pipe()
+	firstChunk := make(chan string, 1)
+	c, srv := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		p, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		buf := make([]byte, 5)
+		n, _ := io.ReadFull(p, buf)
+		firstChunk <- string(buf[:n])
+		io.Copy(io.Discard, p)
+	}))
+
+	done := make(chan error, 1)
+	go func() {
+		body := NewMultipart().File("attachment", "big.bin", pr).Body()
+		done <- c.do(context.Background(), http.MethodPost, mustURL(t, srv.URL), body, nil)
+	}()
+	pw.Write([]byte("first"))
+	select {
+	case got := <-firstChunk:
+		if got != "first" {
+			t.Errorf("first chunk = %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't get the first chunk before the file ended")
+	}
+	pw.Write([]byte(" and the rest"))
+	pw.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultipartSentOnce(t *testing.T) {
+	body := NewMultipart().File("attachment", "pipe", io.MultiReader(strings.NewReader("x"))).Body()
+	if _, err := body.NewRequest(context.Background(), http.MethodPost, "http://example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := body.NewRequest(context.Background(), http.MethodPost, "http://example.com"); err == nil {
+		t.Error("sending an unreplayable body twice succeeded")
+	}
+}
+
+func TestUploadAttachment(t *testing.T) {
+	tests := []struct {
+		name         string
+		reader       func() io.Reader
+		failures     int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "first try", reader: func() io.Reader { return strings.NewReader("log line\n") }, wantAttempts: 1},
+		{name: "replayed on retry", reader: func() io.Reader { return strings.NewReader("log line\n") }, failures: 2, wantAttempts: 3},
+		{
+			name: "replayed from where the reader was",
+			reader: func() io.Reader {
+				r := strings.NewReader("header\nlog line\n")
+				r.Seek(int64(len("header\n")), io.SeekStart)
+				return r
+			},
+			failures:     1,
+			wantAttempts: 2,
+		},
+		{
+			name:         "not retried without a seeker",
+			reader:       func() io.Reader { return io.MultiReader(strings.NewReader("log line\n")) },
+			failures:     1,
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var bodies, keys []string
+			c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/v1/learn-http/issues/issue 1/attachments" {
+					http.NotFound(w, r)
+					return
+				}
+				parts, err := readParts(r)
+				if err != nil || len(parts) != 1 {
+					http.Error(w, "bad body", http.StatusBadRequest)
+					return
+				}
+				mu.Lock()
+				bodies = append(bodies, parts[0].Data)
+				keys = append(keys, r.Header.Get("Idempotency-Key"))
+				attempt := len(bodies)
+				mu.Unlock()
+				if attempt <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				json.NewEncoder(w).Encode(Attachment{Id: "a1", Filename: parts[0].Filename, Size: int64(len(parts[0].Data))})
+			}), WithMiddleware(StageRetry, Retry(RetryPolicy{BaseDelay: time.Millisecond})))
+
+			got, err := c.Issues.UploadAttachment(context.Background(), "issue 1", "app.log", tt.reader())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(bodies) != tt.wantAttempts {
+				t.Fatalf("attempts = %d, want %d", len(bodies), tt.wantAttempts)
+			}
+			for i, b := range bodies {
+				if b != "log line\n" {
+					t.Errorf("attempt %d sent %q", i+1, b)
+				}
+				if keys[i] == "" || keys[i] != keys[0] {
+					t.Errorf("attempt %d idempotency key = %q, first %q", i+1, keys[i], keys[0])
+				}
+			}
+			want := Attachment{Id: "a1", Filename: "app.log", Size: int64(len("log line\n"))}
+			if !tt.wantErr && got != want {
+				t.Errorf("attachment = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
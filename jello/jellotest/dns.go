@@ -0,0 +1,187 @@
+package jellotest
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/internal/dnsmsg"
+)
+
+// DNSServer is a fake DNS server on a local UDP port, for pointing a
+// resolver at with jello.ResolverConfig.Nameserver.
+type DNSServer struct {
+	// Addr is the host:port the server listens on.
+	Addr string
+
+	conn    net.PacketConn
+	queries atomic.Int64
+
+	mu          sync.Mutex
+	records     map[string][]dnsmsg.Resource
+	negativeTTL time.Duration
+}
+
+// NewDNSServer starts a DNSServer that is stopped when the test ends.
+func NewDNSServer(t testing.TB) *DNSServer {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error starting dns server: %v", err)
+	}
+	s := &DNSServer{
+		Addr:    conn.LocalAddr().String(),
+		conn:    conn,
+		records: map[string][]dnsmsg.Resource{},
+	}
+	go s.serve()
+	t.Cleanup(func() { conn.Close() })
+	return s
+}
+
+// Add adds a record. typ is one of A, AAAA, CNAME, NS, MX or TXT and
+// value is written the way dig prints it, e.g. "10 mail.example.com"
+// for MX.
+func (s *DNSServer) Add(name, typ, value string, ttl time.Duration) error {
+	t, ok := dnsmsg.ParseType(typ)
+	if !ok {
+		return fmt.Errorf("unknown record type %q", typ)
+	}
+	r := dnsmsg.Resource{
+		Name:  dnsmsg.Fqdn(name),
+		Type:  t,
+		Class: dnsmsg.ClassINET,
+		TTL:   uint32(ttl / time.Second),
+	}
+	switch t {
+	case dnsmsg.TypeA, dnsmsg.TypeAAAA:
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return err
+		}
+		r.Addr = addr
+	case dnsmsg.TypeCNAME, dnsmsg.TypeNS:
+		r.Target = dnsmsg.Fqdn(value)
+	case dnsmsg.TypeMX:
+		pref, host, ok := strings.Cut(value, " ")
+		n, err := strconv.ParseUint(pref, 10, 16)
+		if !ok || err != nil {
+			return fmt.Errorf("invalid MX value %q, want \"pref host\"", value)
+		}
+		r.Pref, r.Target = uint16(n), dnsmsg.Fqdn(host)
+	case dnsmsg.TypeTXT:
+		r.Text = []string{value}
+	default:
+		return fmt.Errorf("unsupported record type %q", typ)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[r.Name] = append(s.records[r.Name], r)
+	return nil
+}
+
+// SetNegativeTTL makes answers that have no records carry an SOA record
+// with this TTL, so resolvers can cache the miss. 0 leaves it out.
+func (s *DNSServer) SetNegativeTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.negativeTTL = ttl
+}
+
+// Queries returns how many queries the server has answered.
+func (s *DNSServer) Queries() int {
+	return int(s.queries.Load())
+}
+
+func (s *DNSServer) serve() {
+	buf := make([]byte, 4096)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		res, err := s.Answer(buf[:n])
+		if err != nil {
+			continue
+		}
+		s.conn.WriteTo(res, addr)
+	}
+}
+
+// Answer builds the wire-format response to a wire-format query. It is
+// exported so a DNS-over-HTTPS stub can share the same records.
+func (s *DNSServer) Answer(query []byte) ([]byte, error) {
+	q, err := dnsmsg.Unpack(query)
+	if err != nil {
+		return nil, err
+	}
+	s.queries.Add(1)
+
+	res := &dnsmsg.Message{
+		Header: dnsmsg.Header{
+			ID:                 q.ID,
+			Response:           true,
+			RecursionDesired:   q.RecursionDesired,
+			RecursionAvailable: true,
+		},
+		Questions: q.Questions,
+	}
+	if len(q.Questions) != 1 {
+		res.RCode = dnsmsg.RCodeFormatError
+		return res.Pack()
+	}
+	question := q.Questions[0]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := dnsmsg.Fqdn(question.Name)
+	if _, ok := s.records[name]; !ok {
+		res.RCode = dnsmsg.RCodeNameError
+		s.addSOA(res)
+		return res.Pack()
+	}
+	// Follow CNAMEs the way a recursive resolver would.
+	for range 8 {
+		var cname *dnsmsg.Resource
+		for _, r := range s.records[name] {
+			if r.Type == question.Type {
+				r.Name = name
+				res.Answers = append(res.Answers, r)
+			} else if r.Type == dnsmsg.TypeCNAME {
+				cname = &r
+			}
+		}
+		if cname == nil || question.Type == dnsmsg.TypeCNAME {
+			break
+		}
+		res.Answers = append(res.Answers, *cname)
+		name = cname.Target
+	}
+	if len(res.Answers) == 0 {
+		s.addSOA(res)
+	}
+	return res.Pack()
+}
+
+// addSOA adds the SOA record for a negative answer, if a negative TTL
+// is set. Only its TTL matters to resolvers, so the data is minimal: root
+// names and zero serial and timers.
+func (s *DNSServer) addSOA(res *dnsmsg.Message) {
+	if s.negativeTTL <= 0 {
+		return
+	}
+	res.Authorities = append(res.Authorities, dnsmsg.Resource{
+		Name:  ".",
+		Type:  dnsmsg.TypeSOA,
+		Class: dnsmsg.ClassINET,
+		TTL:   uint32(s.negativeTTL / time.Second),
+		Raw:   make([]byte, 2+20),
+	})
+}
@@ -0,0 +1,56 @@
+package jellotest
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// NewDoHServer starts a DNS-over-HTTPS stub at /dns-query that answers
+// GET and POST queries from the records in dns. Point a
+// jello.DoHResolver at srv.URL+"/dns-query" with srv.Client() as its
+// Client. The server is closed when the test ends.
+func NewDoHServer(t testing.TB, dns *DNSServer) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dns-query", func(w http.ResponseWriter, r *http.Request) {
+		var query []byte
+		switch r.Method {
+		case http.MethodGet:
+			q, err := base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+			if err != nil {
+				http.Error(w, "invalid dns parameter", http.StatusBadRequest)
+				return
+			}
+			query = q
+		case http.MethodPost:
+			if r.Header.Get("Content-Type") != "application/dns-message" {
+				http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+				return
+			}
+			q, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+			if err != nil {
+				http.Error(w, "error reading body", http.StatusBadRequest)
+				return
+			}
+			query = q
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		res, err := dns.Answer(query)
+		if err != nil {
+			http.Error(w, "invalid dns message", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(res)
+	})
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
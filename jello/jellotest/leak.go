@@ -0,0 +1,65 @@
+// Package jellotest has helpers for testing code that uses the jello
+// client or plain net/http clients.
+package jellotest
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// LeakCheck wraps next, or http.DefaultTransport if next is nil, and
+// fails t when the test ends if any response body it returned was never
+// closed. An unclosed body holds on to its connection, so it can't be
+// reused and eventually leaks.
+//
+//	hc := &http.Client{Transport: jellotest.LeakCheck(t, nil)}
+//	client, _ := jello.NewClient(srv.URL, jello.WithHTTPClient(hc))
+func LeakCheck(t testing.TB, next http.RoundTripper) http.RoundTripper {
+	t.Helper()
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	d := &leakDetector{next: next, open: map[*trackedBody]string{}}
+	t.Cleanup(func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		for _, req := range d.open {
+			t.Errorf("response body for %s was not closed", req)
+		}
+	})
+	return d
+}
+
+type leakDetector struct {
+	next http.RoundTripper
+
+	mu   sync.Mutex
+	open map[*trackedBody]string
+}
+
+func (d *leakDetector) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := d.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body := &trackedBody{ReadCloser: res.Body, d: d}
+	d.mu.Lock()
+	d.open[body] = req.Method + " " + req.URL.Redacted()
+	d.mu.Unlock()
+	res.Body = body
+	return res, nil
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	d *leakDetector
+}
+
+func (b *trackedBody) Close() error {
+	b.d.mu.Lock()
+	delete(b.d.open, b)
+	b.d.mu.Unlock()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,82 @@
+package jellotest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTB records what LeakCheck reports instead of failing the test.
+type fakeTB struct {
+	testing.TB
+
+	mu       sync.Mutex
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+// finish runs the cleanups like the end of a test would.
+func (f *fakeTB) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestLeakCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		use      func(res *http.Response)
+		wantLeak bool
+	}{
+		{name: "closed", use: func(res *http.Response) { res.Body.Close() }},
+		{name: "read and closed", use: func(res *http.Response) {
+			io.ReadAll(res.Body)
+			res.Body.Close()
+		}},
+		{name: "read but not closed", use: func(res *http.Response) { io.ReadAll(res.Body) }, wantLeak: true},
+		{name: "ignored", use: func(res *http.Response) {}, wantLeak: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &fakeTB{}
+			hc := &http.Client{Transport: LeakCheck(tb, nil)}
+			res, err := hc.Get(srv.URL + "/x")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.use(res)
+			tb.finish()
+			if tt.wantLeak {
+				res.Body.Close()
+			}
+
+			if leaked := len(tb.errors) > 0; leaked != tt.wantLeak {
+				t.Fatalf("reported %q, want leak %v", tb.errors, tt.wantLeak)
+			}
+			if tt.wantLeak && !strings.Contains(tb.errors[0], "GET "+srv.URL+"/x") {
+				t.Errorf("report %q does not name the request", tb.errors[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package jellotest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ProxyOptions configures NewProxy.
+type ProxyOptions struct {
+	// SOCKS5 starts a SOCKS5 proxy instead of an HTTP one.
+	SOCKS5 bool
+	// Username and Password, if set, are required from clients: as Basic
+	// Proxy-Authorization for HTTP, or username/password auth for SOCKS5.
+	Username, Password string
+}
+
+// Proxy is an in-process forward proxy for testing proxy settings. The
+// HTTP proxy tunnels CONNECT requests and forwards absolute-form ones;
+// the SOCKS5 proxy supports the CONNECT command.
+type Proxy struct {
+	// URL is the proxy's address, including the username and password
+	// if there are any, ready for jello.ProxyConfig.URL.
+	URL *url.URL
+
+	opts ProxyOptions
+	ln   net.Listener
+
+	mu      sync.Mutex
+	targets []string
+	conns   map[net.Conn]struct{}
+}
+
+// NewProxy starts a proxy that is stopped when the test ends.
+func NewProxy(t testing.TB, opts ProxyOptions) *Proxy {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error starting proxy: %v", err)
+	}
+	p := &Proxy{
+		URL:   &url.URL{Scheme: "http", Host: ln.Addr().String()},
+		opts:  opts,
+		ln:    ln,
+		conns: map[net.Conn]struct{}{},
+	}
+	if opts.Username != "" {
+		p.URL.User = url.UserPassword(opts.Username, opts.Password)
+	}
+
+	if opts.SOCKS5 {
+		p.URL.Scheme = "socks5"
+		go p.serveSOCKS()
+		t.Cleanup(p.close)
+		return p
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(p.serveHTTP)}
+	go srv.Serve(ln)
+	t.Cleanup(func() {
+		srv.Close()
+		p.close()
+	})
+	return p
+}
+
+// Targets returns the host:port of every request the proxy let through,
+// in order.
+func (p *Proxy) Targets() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.targets)
+}
+
+func (p *Proxy) close() {
+	p.ln.Close()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for c := range p.conns {
+		c.Close()
+	}
+}
+
+func (p *Proxy) track(c net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.conns[c] = struct{}{}
+}
+
+func (p *Proxy) record(target string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.targets = append(p.targets, target)
+}
+
+func (p *Proxy) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if p.opts.Username != "" {
+		// Request.BasicAuth only reads Authorization, so borrow it.
+		h := http.Header{"Authorization": r.Header.Values("Proxy-Authorization")}
+		user, pass, ok := (&http.Request{Header: h}).BasicAuth()
+		if !ok || user != p.opts.Username || pass != p.opts.Password {
+			w.Header().Set("Proxy-Authenticate", `Basic realm="jellotest"`)
+			http.Error(w, "proxy authentication required", http.StatusProxyAuthRequired)
+			return
+		}
+	}
+
+	if r.Method == http.MethodConnect {
+		p.connect(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "not a proxy request", http.StatusBadRequest)
+		return
+	}
+	p.record(r.URL.Host)
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	out.Header.Del("Proxy-Authorization")
+	out.Header.Del("Proxy-Connection")
+	res, err := http.DefaultTransport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer res.Body.Close()
+	for k, v := range res.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(res.StatusCode)
+	io.Copy(w, res.Body)
+}
+
+func (p *Proxy) connect(w http.ResponseWriter, r *http.Request) {
+	upstream, err := net.DialTimeout("tcp", r.Host, 5*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	p.record(r.Host)
+	conn, buf, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	p.track(conn)
+	p.track(upstream)
+	conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+	pipe(conn, buf.Reader, upstream)
+}
+
+func (p *Proxy) serveSOCKS() {
+	for {
+		conn, err := p.ln.Accept()
+		if err != nil {
+			return
+		}
+		p.track(conn)
+		go func() {
+			br := bufio.NewReader(conn)
+			upstream, err := p.socksHandshake(conn, br)
+			if err != nil {
+				conn.Close()
+				return
+			}
+			p.track(upstream)
+			pipe(conn, br, upstream)
+		}()
+	}
+}
+
+// SOCKS5 reply codes from RFC 1928.
+const (
+	socksSucceeded           = 0
+	socksGeneralFailure      = 1
+	socksCommandNotSupported = 7
+	socksAddrNotSupported    = 8
+)
+
+// socksHandshake runs the RFC 1928 greeting, the RFC 1929 username and
+// password check if required, and a CONNECT request.
+func (p *Proxy) socksHandshake(conn net.Conn, br *bufio.Reader) (net.Conn, error) {
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetDeadline(time.Time{})
+
+	hello := make([]byte, 2)
+	if _, err := io.ReadFull(br, hello); err != nil {
+		return nil, err
+	}
+	if hello[0] != 5 {
+		return nil, errors.New("not socks5")
+	}
+	methods := make([]byte, hello[1])
+	if _, err := io.ReadFull(br, methods); err != nil {
+		return nil, err
+	}
+	method := byte(0) // no auth
+	if p.opts.Username != "" {
+		method = 2 // username/password
+	}
+	if !slices.Contains(methods, method) {
+		conn.Write([]byte{5, 0xff})
+		return nil, errors.New("no acceptable auth method")
+	}
+	conn.Write([]byte{5, method})
+
+	if method == 2 {
+		user, pass, err := readSOCKSAuth(br)
+		if err != nil {
+			return nil, err
+		}
+		if user != p.opts.Username || pass != p.opts.Password {
+			conn.Write([]byte{1, 1})
+			return nil, errors.New("bad credentials")
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	reply := func(code byte) {
+		conn.Write([]byte{5, code, 0, 1, 0, 0, 0, 0, 0, 0})
+	}
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(br, req); err != nil {
+		return nil, err
+	}
+	if req[1] != 1 {
+		reply(socksCommandNotSupported)
+		return nil, errors.New("only CONNECT is supported")
+	}
+	var host string
+	switch req[3] {
+	case 1, 4:
+		addr := make([]byte, 4)
+		if req[3] == 4 {
+			addr = make([]byte, 16)
+		}
+		if _, err := io.ReadFull(br, addr); err != nil {
+			return nil, err
+		}
+		host = net.IP(addr).String()
+	case 3:
+		n, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		name := make([]byte, n)
+		if _, err := io.ReadFull(br, name); err != nil {
+			return nil, err
+		}
+		host = string(name)
+	default:
+		reply(socksAddrNotSupported)
+		return nil, errors.New("unknown address type")
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(br, port); err != nil {
+		return nil, err
+	}
+	target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+
+	upstream, err := net.DialTimeout("tcp", target, 5*time.Second)
+	if err != nil {
+		reply(socksGeneralFailure)
+		return nil, err
+	}
+	p.record(target)
+	reply(socksSucceeded)
+	return upstream, nil
+}
+
+func readSOCKSAuth(br *bufio.Reader) (user, pass string, err error) {
+	readString := func() (string, error) {
+		n, err := br.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(br, b)
+		return string(b), err
+	}
+	version, err := br.ReadByte()
+	if err != nil {
+		return "", "", err
+	}
+	if version != 1 {
+		return "", "", errors.New("unknown auth version")
+	}
+	if user, err = readString(); err != nil {
+		return "", "", err
+	}
+	pass, err = readString()
+	return user, pass, err
+}
+
+// pipe copies between client and upstream until either side is done.
+// Bytes the client sent that were already buffered in r go first.
+func pipe(client net.Conn, r io.Reader, upstream net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, r)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(client, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+	client.Close()
+	upstream.Close()
+}
@@ -0,0 +1,231 @@
+package jellotest
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+)
+
+// Tracer is a jello.Tracer that keeps every span in memory.
+type Tracer struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+type spanKey struct{}
+
+// Start starts a span, as a child of the span in ctx if there is one.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, jello.Span) {
+	s := &Span{Name: name, Start: time.Now(), attributes: map[string]any{}}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok {
+		s.Parent = parent.ctx.SpanID
+		s.ctx.TraceID = parent.ctx.TraceID
+	} else {
+		rand.Read(s.ctx.TraceID[:])
+	}
+	rand.Read(s.ctx.SpanID[:])
+	s.ctx.Sampled = true
+
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Spans returns the spans started so far.
+func (t *Tracer) Spans() []*Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.spans)
+}
+
+// Span is a span recorded by Tracer. What the client records on it
+// can change until it ends, so it is read through methods that copy.
+type Span struct {
+	Name   string
+	Parent [8]byte
+	Start  time.Time
+
+	mu         sync.Mutex
+	ctx        jello.SpanContext
+	end        time.Time
+	attributes map[string]any
+	events     []Event
+	errors     []error
+}
+
+// Event is an event added to a Span.
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]any
+}
+
+func (s *Span) SetAttributes(attrs ...jello.Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range attrs {
+		s.attributes[a.Key] = a.Value
+	}
+}
+
+func (s *Span) AddEvent(name string, attrs ...jello.Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := Event{Name: name, Time: time.Now(), Attributes: map[string]any{}}
+	for _, a := range attrs {
+		e.Attributes[a.Key] = a.Value
+	}
+	s.events = append(s.events, e)
+}
+
+func (s *Span) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors = append(s.errors, err)
+}
+
+func (s *Span) SpanContext() jello.SpanContext {
+	return s.ctx
+}
+
+func (s *Span) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.end.IsZero() {
+		s.end = time.Now()
+	}
+}
+
+// Ended reports whether End has been called.
+func (s *Span) Ended() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.end.IsZero()
+}
+
+// EndTime returns when End was first called, or the zero time.
+func (s *Span) EndTime() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.end
+}
+
+// Attributes returns a copy of the span's attributes.
+func (s *Span) Attributes() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.attributes)
+}
+
+// Attribute returns the value of one attribute, or nil.
+func (s *Span) Attribute(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attributes[key]
+}
+
+// Events returns the events added so far, in order.
+func (s *Span) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.events)
+}
+
+// Errors returns the errors recorded so far.
+func (s *Span) Errors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.errors)
+}
+
+// DefaultBuckets are the histogram bucket bounds, in seconds, that the
+// OpenTelemetry HTTP conventions suggest for request durations.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+// Metrics is a jello.Metrics that keeps counters and histograms in
+// memory, keyed by name and attributes.
+type Metrics struct {
+	// Buckets are the histogram upper bounds. Defaults to DefaultBuckets.
+	Buckets []float64
+
+	mu         sync.Mutex
+	counters   map[string]int64
+	histograms map[string]*Histogram
+}
+
+// Histogram is a snapshot of recorded values. Counts[i] is the number of
+// values <= Bounds[i]; the last count is for values above every bound.
+type Histogram struct {
+	Bounds []float64
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+func (m *Metrics) Add(ctx context.Context, name string, n int64, attrs ...jello.Attribute) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counters == nil {
+		m.counters = map[string]int64{}
+	}
+	m.counters[metricKey(name, attrs)] += n
+}
+
+func (m *Metrics) Record(ctx context.Context, name string, value float64, attrs ...jello.Attribute) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.histograms == nil {
+		m.histograms = map[string]*Histogram{}
+	}
+	key := metricKey(name, attrs)
+	h, ok := m.histograms[key]
+	if !ok {
+		bounds := m.Buckets
+		if bounds == nil {
+			bounds = DefaultBuckets
+		}
+		h = &Histogram{Bounds: bounds, Counts: make([]uint64, len(bounds)+1)}
+		m.histograms[key] = h
+	}
+	h.Counts[sort.SearchFloat64s(h.Bounds, value)]++
+	h.Count++
+	h.Sum += value
+}
+
+// Count returns the counter for name with exactly attrs.
+func (m *Metrics) Count(name string, attrs ...jello.Attribute) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counters[metricKey(name, attrs)]
+}
+
+// Histogram returns the histogram for name with exactly attrs.
+func (m *Metrics) Histogram(name string, attrs ...jello.Attribute) Histogram {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.histograms[metricKey(name, attrs)]
+	if !ok {
+		return Histogram{}
+	}
+	c := *h
+	c.Counts = slices.Clone(h.Counts)
+	return c
+}
+
+func metricKey(name string, attrs []jello.Attribute) string {
+	parts := make([]string, len(attrs))
+	for i, a := range attrs {
+		parts[i] = fmt.Sprintf("%s=%v", a.Key, a.Value)
+	}
+	sort.Strings(parts)
+	return name + "{" + strings.Join(parts, ",") + "}"
+}
@@ -0,0 +1,73 @@
+package jellotest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+)
+
+func TestMetrics(t *testing.T) {
+	m := &Metrics{Buckets: []float64{0.1, 1}}
+	ctx := context.Background()
+	a := jello.Attribute{Key: "a", Value: 1}
+	b := jello.Attribute{Key: "b", Value: "x"}
+
+	m.Add(ctx, "count", 2, a, b)
+	m.Add(ctx, "count", 3, b, a)
+	m.Add(ctx, "count", 5, a)
+	if got := m.Count("count", a, b); got != 5 {
+		t.Errorf("count{a,b} = %d, want 5 whatever the attribute order", got)
+	}
+	if got := m.Count("count", a); got != 5 {
+		t.Errorf("count{a} = %d, want 5", got)
+	}
+
+	for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+		m.Record(ctx, "duration", v)
+	}
+	h := m.Histogram("duration")
+	want := []uint64{2, 1, 1}
+	for i := range want {
+		if h.Counts[i] != want[i] {
+			t.Fatalf("counts = %v, want %v", h.Counts, want)
+		}
+	}
+	if h.Count != 4 || h.Sum != 3.65 {
+		t.Errorf("count = %d, sum = %v", h.Count, h.Sum)
+	}
+}
+
+// TestSpanConcurrentReads is meant for -race: the client adds events
+// from connection goroutines while a test may be reading them.
+func TestSpanConcurrentReads(t *testing.T) {
+	tracer := &Tracer{}
+	_, js := tracer.Start(context.Background(), "GET")
+	span := js.(*Span)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range 100 {
+			span.SetAttributes(jello.Attribute{Key: "n", Value: i})
+			span.AddEvent("tick")
+		}
+		span.End()
+	}()
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			attrs := span.Attributes()
+			attrs["mine"] = true
+			_ = span.Events()
+			_ = span.Attribute("n")
+		}
+	}()
+	wg.Wait()
+
+	if len(span.Events()) != 100 || span.Attribute("n") != 99 || span.Attribute("mine") != nil {
+		t.Errorf("events = %d, n = %v, mine = %v", len(span.Events()), span.Attribute("n"), span.Attribute("mine"))
+	}
+}
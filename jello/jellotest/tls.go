@@ -0,0 +1,104 @@
+package jellotest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// CertOptions describes a certificate made by NewCertificate.
+type CertOptions struct {
+	// CommonName is the subject common name.
+	CommonName string
+	// Hosts are the DNS names and IP addresses the certificate is valid
+	// for. Defaults to "localhost" and 127.0.0.1, which is what
+	// httptest servers listen on.
+	Hosts []string
+	// NotBefore and NotAfter bound the validity. They default to an hour
+	// ago and a day from now; set NotAfter in the past for an expired
+	// certificate.
+	NotBefore, NotAfter time.Time
+	// IsCA makes a certificate that can sign others.
+	IsCA bool
+	// Parent signs the certificate. If nil it is self-signed.
+	Parent *tls.Certificate
+}
+
+// NewCertificate creates an ECDSA P-256 certificate and key for testing
+// TLS servers and mutual TLS clients. The leaf is parsed, so
+// cert.Leaf is set.
+func NewCertificate(t testing.TB, opts CertOptions) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		t.Fatalf("error generating serial: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: opts.CommonName},
+		NotBefore:    opts.NotBefore,
+		NotAfter:     opts.NotAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if tmpl.NotBefore.IsZero() {
+		tmpl.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if tmpl.NotAfter.IsZero() {
+		tmpl.NotAfter = time.Now().Add(24 * time.Hour)
+	}
+	if opts.IsCA {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+	hosts := opts.Hosts
+	if hosts == nil && !opts.IsCA {
+		hosts = []string{"localhost", "127.0.0.1"}
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	parent, signer := tmpl, any(key)
+	if opts.Parent != nil {
+		parent, signer = opts.Parent.Leaf, opts.Parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatalf("error creating certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("error parsing certificate: %v", err)
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+	if opts.Parent != nil {
+		cert.Certificate = append(cert.Certificate, opts.Parent.Certificate...)
+	}
+	return cert
+}
+
+// CertPEM encodes the leaf of cert as PEM, e.g. for
+// jello.TLSConfig.RootCAs. For an httptest TLS server use
+// CertPEM(srv.TLS.Certificates[0]).
+func CertPEM(cert tls.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+}
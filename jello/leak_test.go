@@ -0,0 +1,54 @@
+package jello_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+	"github.com/JavierLU90/http_clients_go/jello/jellotest"
+)
+
+// TestClientClosesBodies checks that every path through the client
+// closes the response body, whether the call succeeds or not.
+func TestClientClosesBodies(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr bool
+	}{
+		{name: "ok", status: http.StatusOK, body: `[{"name":"Gondor"}]`},
+		{name: "server error", status: http.StatusInternalServerError, body: `{"error":"boom"}`, wantErr: true},
+		{name: "not found", status: http.StatusNotFound, body: strings.Repeat("x", 100<<10), wantErr: true},
+		{name: "bad json", status: http.StatusOK, body: `[{"name":`, wantErr: true},
+		{name: "too large", status: http.StatusOK, body: "[" + strings.Repeat(" ", 2<<10) + "]", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			hc := &http.Client{Transport: jellotest.LeakCheck(t, nil)}
+			c, err := jello.NewClient(srv.URL, jello.WithHTTPClient(hc), jello.WithMaxBodySize(1<<10))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.Locations.List(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			for _, err := range c.Projects.List(context.Background(), nil) {
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("list error = %v, want error %v", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package jello
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// LocationID is the UUID of a location, e.g.
+// "52fdfc07-2182-454f-963f-5f0f9a621d72".
+type LocationID string
+
+// ParseLocationID checks that s is a UUID and returns it as a LocationID.
+func ParseLocationID(s string) (LocationID, error) {
+	id := LocationID(s)
+	if err := id.Validate(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Validate reports whether id is a UUID in the 8-4-4-4-12 hex form.
+func (id LocationID) Validate() error {
+	if !isUUID(string(id)) {
+		return fmt.Errorf("%w: location id %q is not a uuid", ErrInvalidID, string(id))
+	}
+	return nil
+}
+
+func (id LocationID) String() string {
+	return string(id)
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			c := s[i]
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Location is a single location resource.
+type Location struct {
+	Id          LocationID `json:"id,omitempty"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+}
+
+// LocationsService handles the /locations endpoints.
+type LocationsService struct {
+	client *Client
+}
+
+// List returns every location.
+func (s *LocationsService) List(ctx context.Context) ([]Location, error) {
+	u, err := s.client.endpoint("locations")
+	if err != nil {
+		return nil, err
+	}
+	var locations []Location
+	if err := s.client.do(ctx, http.MethodGet, u, nil, &locations); err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// Get returns the location with the given id.
+func (s *LocationsService) Get(ctx context.Context, id LocationID) (Location, error) {
+	u, err := s.locationURL(id)
+	if err != nil {
+		return Location{}, err
+	}
+	var location Location
+	if err := s.client.do(ctx, http.MethodGet, u, nil, &location); err != nil {
+		return Location{}, err
+	}
+	return location, nil
+}
+
+// Create adds a new location. The server assigns the id.
+func (s *LocationsService) Create(ctx context.Context, location Location) (Location, error) {
+	u, err := s.client.endpoint("locations")
+	if err != nil {
+		return Location{}, err
+	}
+	var created Location
+	if err := s.client.do(ctx, http.MethodPost, u, location, &created); err != nil {
+		return Location{}, err
+	}
+	return created, nil
+}
+
+// Update replaces the location with the given id.
+func (s *LocationsService) Update(ctx context.Context, id LocationID, location Location) (Location, error) {
+	u, err := s.locationURL(id)
+	if err != nil {
+		return Location{}, err
+	}
+	location.Id = id
+	var updated Location
+	if err := s.client.do(ctx, http.MethodPut, u, location, &updated); err != nil {
+		return Location{}, err
+	}
+	return updated, nil
+}
+
+// Delete removes the location with the given id.
+func (s *LocationsService) Delete(ctx context.Context, id LocationID) error {
+	u, err := s.locationURL(id)
+	if err != nil {
+		return err
+	}
+	return s.client.do(ctx, http.MethodDelete, u, nil, nil)
+}
+
+func (s *LocationsService) locationURL(id LocationID) (*url.URL, error) {
+	if err := id.Validate(); err != nil {
+		return nil, err
+	}
+	return s.client.endpoint("locations", id.String())
+}
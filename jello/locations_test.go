@@ -0,0 +1,159 @@
+package jello
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+const testLocationID LocationID = "52fdfc07-2182-454f-963f-5f0f9a621d72"
+
+func TestParseLocationID(t *testing.T) {
+	tests := []struct {
+		in string
+		ok bool
+	}{
+		{"52fdfc07-2182-454f-963f-5f0f9a621d72", true},
+		{"52FDFC07-2182-454F-963F-5F0F9A621D72", true},
+		{"52fdfc07-2182-454f-963f-5f0f9a621d7", false},
+		{"52fdfc07x2182-454f-963f-5f0f9a621d72", false},
+		{"52fdfc07-2182-454f-963f-5f0f9a621d7g", false},
+		{"../../admin", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		id, err := ParseLocationID(tt.in)
+		if tt.ok != (err == nil) {
+			t.Errorf("ParseLocationID(%q) error = %v, want ok %v", tt.in, err, tt.ok)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrInvalidID) {
+			t.Errorf("ParseLocationID(%q) error = %v, want ErrInvalidID", tt.in, err)
+		}
+		if tt.ok && id.String() != tt.in {
+			t.Errorf("ParseLocationID(%q) = %q", tt.in, id)
+		}
+	}
+}
+
+func TestLocations(t *testing.T) {
+	type call struct {
+		method, path string
+		body         Location
+	}
+	var got []call
+	c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body Location
+		if r.Body != nil {
+			json.NewDecoder(r.Body).Decode(&body)
+		}
+		got = append(got, call{r.Method, r.URL.EscapedPath(), body})
+		switch {
+		case r.URL.Path == "/v1/learn-http/locations/"+testLocationID.String() && r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/v1/learn-http/locations" && r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode([]Location{{Id: testLocationID, Name: "Bag End"}})
+		case r.Method == http.MethodPost:
+			body.Id = testLocationID
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(body)
+		case r.URL.Path == "/v1/learn-http/locations/"+testLocationID.String():
+			body.Id = testLocationID
+			if body.Name == "" {
+				body.Name = "Bag End"
+			}
+			json.NewEncoder(w).Encode(body)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	ctx := context.Background()
+	other := LocationID("00000000-0000-4000-8000-000000000000")
+
+	tests := []struct {
+		name    string
+		run     func() (any, error)
+		want    any
+		wantErr error
+		call    *call
+	}{
+		{
+			name: "list",
+			run:  func() (any, error) { return c.Locations.List(ctx) },
+			want: []Location{{Id: testLocationID, Name: "Bag End"}},
+			call: &call{method: "GET", path: "/v1/learn-http/locations"},
+		},
+		{
+			name: "get",
+			run:  func() (any, error) { return c.Locations.Get(ctx, testLocationID) },
+			want: Location{Id: testLocationID, Name: "Bag End"},
+			call: &call{method: "GET", path: "/v1/learn-http/locations/" + testLocationID.String()},
+		},
+		{
+			name: "create",
+			run:  func() (any, error) { return c.Locations.Create(ctx, Location{Name: "Rivendell"}) },
+			want: Location{Id: testLocationID, Name: "Rivendell"},
+			call: &call{method: "POST", path: "/v1/learn-http/locations", body: Location{Name: "Rivendell"}},
+		},
+		{
+			name: "update sends the id in the body",
+			run: func() (any, error) {
+				return c.Locations.Update(ctx, testLocationID, Location{Name: "Moria"})
+			},
+			want: Location{Id: testLocationID, Name: "Moria"},
+			call: &call{method: "PUT", path: "/v1/learn-http/locations/" + testLocationID.String(), body: Location{Id: testLocationID, Name: "Moria"}},
+		},
+		{
+			name: "delete",
+			run:  func() (any, error) { return nil, c.Locations.Delete(ctx, testLocationID) },
+			call: &call{method: "DELETE", path: "/v1/learn-http/locations/" + testLocationID.String()},
+		},
+		{
+			name:    "missing location",
+			run:     func() (any, error) { return c.Locations.Get(ctx, other) },
+			wantErr: ErrNotFound,
+			call:    &call{method: "GET", path: "/v1/learn-http/locations/" + other.String()},
+		},
+		{
+			name:    "invalid id is not sent",
+			run:     func() (any, error) { return c.Locations.Get(ctx, "../admin") },
+			wantErr: ErrInvalidID,
+		},
+		{
+			name:    "invalid id on delete is not sent",
+			run:     func() (any, error) { return nil, c.Locations.Delete(ctx, "1 OR 1=1") },
+			wantErr: ErrInvalidID,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			res, err := tt.run()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != nil && !equalJSON(res, tt.want) {
+				t.Errorf("got %+v, want %+v", res, tt.want)
+			}
+			switch {
+			case tt.call == nil && len(got) != 0:
+				t.Errorf("sent %+v, want no request", got)
+			case tt.call != nil && (len(got) != 1 || got[0] != *tt.call):
+				t.Errorf("sent %+v, want %+v", got, *tt.call)
+			}
+		})
+	}
+}
+
+// equalJSON reports whether a and b encode to the same JSON.
+func equalJSON(a, b any) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
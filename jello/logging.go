@@ -0,0 +1,246 @@
+package jello
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "REDACTED"
+
+// DefaultRedactHeaders are the headers whose values Logging hides unless
+// LogOptions says otherwise.
+var DefaultRedactHeaders = []string{"X-API-Key", "Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// DefaultRedactQuery are the query parameters whose values Logging hides
+// unless LogOptions says otherwise.
+var DefaultRedactQuery = []string{"api_key", "apikey", "key", "token", "access_token", "password", "secret", "signature"}
+
+// LogOptions configures Logging.
+type LogOptions struct {
+	// RedactHeaders replaces DefaultRedactHeaders. Names are not case
+	// sensitive.
+	RedactHeaders []string
+	// RedactQuery replaces DefaultRedactQuery. Names are not case
+	// sensitive.
+	RedactQuery []string
+	// DumpHeaders logs request and response headers at debug level.
+	DumpHeaders bool
+	// DumpBodies logs the start of request and response bodies at debug
+	// level, up to MaxBodyDump bytes.
+	DumpBodies  bool
+	MaxBodyDump int
+}
+
+// Logging logs every attempt to logger: method, URL, status, duration,
+// bytes sent and received, and the attempt number when retrying. It is
+// meant for StageObserve, so it sees requests after auth is added.
+//
+// The line is written when the response body is closed, so the
+// duration and byte count cover the whole body. Secrets in headers and
+// the query string are redacted. A nil logger uses slog.Default and nil
+// opts uses the defaults.
+func Logging(logger *slog.Logger, opts *LogOptions) Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	var o LogOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.RedactHeaders == nil {
+		o.RedactHeaders = DefaultRedactHeaders
+	}
+	if o.RedactQuery == nil {
+		o.RedactQuery = DefaultRedactQuery
+	}
+	if o.MaxBodyDump <= 0 {
+		o.MaxBodyDump = 1 << 10
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			debug := logger.Enabled(ctx, slog.LevelDebug)
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("url", redactURL(req.URL, o.RedactQuery)),
+				slog.Int("attempt", Attempt(ctx)),
+			}
+			if id := req.Header.Get(RequestIDHeader); id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+			if req.ContentLength > 0 {
+				attrs = append(attrs, slog.Int64("request_bytes", req.ContentLength))
+			}
+			if debug && o.DumpHeaders {
+				attrs = append(attrs, slog.Any("request_headers", redactHeaders(req.Header, o.RedactHeaders)))
+			}
+			// The request body is captured as the transport sends it.
+			// Reading it ahead through GetBody would move a streamed
+			// body, such as a multipart file, under the real request.
+			var reqDump *dumpBuffer
+			if debug && o.DumpBodies && req.Body != nil && req.Body != http.NoBody {
+				reqDump = &dumpBuffer{max: o.MaxBodyDump}
+				req = req.Clone(ctx)
+				req.Body = &dumpingBody{ReadCloser: req.Body, dump: reqDump}
+			}
+			requestBody := func() {
+				if reqDump != nil {
+					attrs = append(attrs, slog.String("request_body", reqDump.String()))
+				}
+			}
+
+			start := time.Now()
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				requestBody()
+				attrs = append(attrs,
+					slog.Duration("duration", time.Since(start)),
+					slog.String("error", err.Error()))
+				logger.LogAttrs(ctx, slog.LevelError, "http request failed", attrs...)
+				return nil, err
+			}
+
+			attrs = append(attrs, slog.Int("status", res.StatusCode))
+			if debug && o.DumpHeaders {
+				attrs = append(attrs, slog.Any("response_headers", redactHeaders(res.Header, o.RedactHeaders)))
+			}
+			body := &loggedBody{ReadCloser: res.Body, done: func(n int64, dump []byte, readErr error) {
+				requestBody()
+				attrs = append(attrs,
+					slog.Duration("duration", time.Since(start)),
+					slog.Int64("response_bytes", n))
+				if dump != nil {
+					attrs = append(attrs, slog.String("response_body", string(dump)))
+				}
+				if readErr != nil {
+					attrs = append(attrs, slog.String("error", readErr.Error()))
+				}
+				level := slog.LevelInfo
+				if res.StatusCode >= 400 || readErr != nil {
+					level = slog.LevelWarn
+				}
+				logger.LogAttrs(ctx, level, "http request", attrs...)
+			}}
+			if debug && o.DumpBodies {
+				body.dump = &bytes.Buffer{}
+				body.max = o.MaxBodyDump
+			}
+			res.Body = body
+			return res, nil
+		})
+	}
+}
+
+// loggedBody counts the bytes read and calls done once on Close.
+type loggedBody struct {
+	io.ReadCloser
+	n    int64
+	err  error
+	dump *bytes.Buffer
+	max  int
+	once sync.Once
+	done func(n int64, dump []byte, err error)
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if b.dump != nil && b.dump.Len() < b.max {
+		b.dump.Write(p[:min(n, b.max-b.dump.Len())])
+	}
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		var dump []byte
+		if b.dump != nil {
+			dump = b.dump.Bytes()
+		}
+		b.done(b.n, dump, b.err)
+	})
+	return err
+}
+
+// redactURL hides the password and the values of secret query
+// parameters in u.
+func redactURL(u *url.URL, secrets []string) string {
+	if u.RawQuery == "" {
+		return u.Redacted()
+	}
+	c := *u
+	q := c.Query()
+	for name := range q {
+		if containsFold(secrets, name) {
+			q[name] = []string{redacted}
+		}
+	}
+	c.RawQuery = q.Encode()
+	return c.Redacted()
+}
+
+// redactHeaders returns a copy of h with the values of secret headers
+// hidden.
+func redactHeaders(h http.Header, secrets []string) http.Header {
+	c := h.Clone()
+	for name := range c {
+		if containsFold(secrets, name) {
+			c[name] = []string{redacted}
+		}
+	}
+	return c
+}
+
+// dumpBuffer keeps the first max bytes written to it. It is locked
+// since the transport may still be sending a request body when the
+// response is logged.
+type dumpBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	max int
+}
+
+func (d *dumpBuffer) write(p []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.buf.Len() < d.max {
+		d.buf.Write(p[:min(len(p), d.max-d.buf.Len())])
+	}
+}
+
+func (d *dumpBuffer) String() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.buf.String()
+}
+
+// dumpingBody copies the start of a request body into dump as it is
+// read.
+type dumpingBody struct {
+	io.ReadCloser
+	dump *dumpBuffer
+}
+
+func (b *dumpingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.dump.write(p[:n])
+	return n, err
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool {
+		return strings.EqualFold(v, s)
+	})
+}
@@ -0,0 +1,305 @@
+package jello
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	mathrand "math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Middleware wraps a RoundTripper to change requests on the way out or
+// responses on the way back. Middleware must not modify the request it
+// is given; clone it first, as the built-ins do.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc turns a function into an http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps rt with mws. The first middleware is the outermost one: it
+// sees the request first and the response last.
+func Chain(rt http.RoundTripper, mws ...Middleware) http.RoundTripper {
+	for i := len(mws) - 1; i >= 0; i-- {
+		rt = mws[i](rt)
+	}
+	return rt
+}
+
+// Stage is where in the client's chain a middleware runs. Earlier
+// stages wrap later ones. From the outside in:
+//
+//	StageHeaders  default headers, User-Agent and request IDs, set once
+//	              per call so every retry carries the same request ID
+//	StageCache    a cached response skips everything below
+//	StageRetry    each attempt goes through every stage below it
+//	StageAuth     credentials are added per attempt, so a refreshed
+//	              token is picked up by the retry
+//	StageObserve  logging and metrics see each attempt as it is sent
+//
+// Below all stages sit the circuit breaker and the rate limiter, so
+// every attempt is checked by them, and then the transport. Within a
+// stage, middleware runs in the order it was added.
+type Stage int
+
+const (
+	StageHeaders Stage = iota
+	StageCache
+	StageRetry
+	StageAuth
+	StageObserve
+)
+
+type stagedMiddleware struct {
+	stage Stage
+	mw    Middleware
+}
+
+// WithMiddleware adds mws to the client's chain at stage.
+func WithMiddleware(stage Stage, mws ...Middleware) Option {
+	return func(c *Client) {
+		for _, mw := range mws {
+			c.middleware = append(c.middleware, stagedMiddleware{stage: stage, mw: mw})
+		}
+	}
+}
+
+// buildChain wraps rt with the client's middleware in stage order.
+// Innermost is stripSensitive, so it sees headers every middleware set.
+func (c *Client) buildChain(rt http.RoundTripper) http.RoundTripper {
+	sensitive := DefaultSensitiveHeaders
+	if c.redirects != nil && c.redirects.SensitiveHeaders != nil {
+		sensitive = c.redirects.SensitiveHeaders
+	}
+	rt = stripSensitive(sensitive, c.httpClient.Jar)(rt)
+
+	if c.rateLimiter != nil {
+		rt = c.rateLimiter.Middleware(rt)
+	}
+	// The breaker goes outside the limiter so an open circuit doesn't
+	// wait for or use up rate limit tokens.
+	if c.breaker != nil {
+		rt = c.breaker.Middleware(rt)
+	}
+
+	staged := slices.Clone(c.middleware)
+	slices.SortStableFunc(staged, func(a, b stagedMiddleware) int {
+		return int(a.stage) - int(b.stage)
+	})
+	mws := make([]Middleware, len(staged))
+	for i, s := range staged {
+		mws[i] = s.mw
+	}
+	return Chain(rt, mws...)
+}
+
+// withHeaders returns next wrapped so edit can change a copy of each
+// request's headers.
+func withHeaders(next http.RoundTripper, edit func(req *http.Request)) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		edit(req)
+		return next.RoundTrip(req)
+	})
+}
+
+// DefaultHeaders sets each header in h that the request doesn't
+// already have.
+func DefaultHeaders(h http.Header) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return withHeaders(next, func(req *http.Request) {
+			for name, values := range h {
+				if _, ok := req.Header[name]; !ok {
+					req.Header[name] = slices.Clone(values)
+				}
+			}
+		})
+	}
+}
+
+// UserAgent sets the User-Agent header.
+func UserAgent(ua string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return withHeaders(next, func(req *http.Request) {
+			req.Header.Set("User-Agent", ua)
+		})
+	}
+}
+
+// RequestIDHeader is the header RequestID sets.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID gives each request a random X-Request-ID unless it already
+// has one.
+func RequestID() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return withHeaders(next, func(req *http.Request) {
+			if req.Header.Get(RequestIDHeader) == "" {
+				req.Header.Set(RequestIDHeader, newRequestID())
+			}
+		})
+	}
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// APIKey sets the X-API-Key header.
+func APIKey(key string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return withHeaders(next, func(req *http.Request) {
+			req.Header.Set("X-API-Key", key)
+		})
+	}
+}
+
+// BasicAuth sets HTTP Basic credentials.
+func BasicAuth(username, password string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return withHeaders(next, func(req *http.Request) {
+			req.SetBasicAuth(username, password)
+		})
+	}
+}
+
+// BearerToken sets an "Authorization: Bearer" header with the token
+// returned by token, which is called for every attempt so it can hand
+// out refreshed tokens.
+func BearerToken(token func(ctx context.Context) (string, error)) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			t, err := token(req.Context())
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", "Bearer "+t)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+type attemptKey struct{}
+
+// Attempt returns which attempt of a request ctx belongs to, starting at
+// 1. Middleware below StageRetry can use it to tell retries apart.
+func Attempt(ctx context.Context) int {
+	if n, ok := ctx.Value(attemptKey{}).(int); ok {
+		return n
+	}
+	return 1
+}
+
+// RetryPolicy configures Retry. Zero fields use the defaults noted.
+type RetryPolicy struct {
+	// MaxAttempts counts the first try too. Defaults to 3.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry, doubled each time
+	// with some jitter. Defaults to 200ms.
+	BaseDelay time.Duration
+	// MaxDelay caps the wait, including one asked for by Retry-After.
+	// Defaults to 5s.
+	MaxDelay time.Duration
+	// RetryOn decides whether to retry. By default 429, 502, 503, 504 and
+	// transport errors are retried, except for the context ending, an
+	// open circuit or the rate limiter failing fast.
+	RetryOn func(res *http.Response, err error) bool
+}
+
+// Retry retries failed requests with exponential backoff. Only
+// idempotent methods, or requests with an Idempotency-Key header, are
+// retried, and only if their body can be replayed.
+func Retry(p RetryPolicy) Middleware {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = 200 * time.Millisecond
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 5 * time.Second
+	}
+	if p.RetryOn == nil {
+		p.RetryOn = shouldRetry
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			for attempt := 1; ; attempt++ {
+				try := req.WithContext(context.WithValue(ctx, attemptKey{}, attempt))
+				if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+					body, err := req.GetBody()
+					if err != nil {
+						return nil, err
+					}
+					try.Body = body
+				}
+
+				res, err := next.RoundTrip(try)
+				if attempt >= p.MaxAttempts || !canRetry(req) || !p.RetryOn(res, err) {
+					return res, err
+				}
+
+				delay := backoff(p, attempt, res, time.Now())
+				if res != nil {
+					drainAndClose(res.Body)
+				}
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, ctx.Err()
+				}
+			}
+		})
+	}
+}
+
+func canRetry(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		return !isContextErr(err) && !errors.Is(err, ErrCircuitOpen) && !errors.Is(err, ErrRateLimited)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns how long to wait before the next attempt. A
+// Retry-After header, in seconds or as an HTTP date, wins over the
+// exponential delay.
+func backoff(p RetryPolicy, attempt int, res *http.Response, now time.Time) time.Duration {
+	if res != nil {
+		if at, ok := parseRetryAfter(res.Header.Get("Retry-After"), now); ok {
+			return min(max(at.Sub(now), 0), p.MaxDelay)
+		}
+	}
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	// Up to 20% jitter so clients that failed together don't retry together.
+	return d - time.Duration(mathrand.Int64N(int64(d)/5+1))
+}
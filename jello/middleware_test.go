@@ -0,0 +1,239 @@
+package jello
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordHeaders is a handler that keeps the headers of each request.
+type recordHeaders struct {
+	mu      sync.Mutex
+	headers []http.Header
+	bodies  []string
+}
+
+func (h *recordHeaders) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	h.mu.Lock()
+	h.headers = append(h.headers, r.Header.Clone())
+	h.bodies = append(h.bodies, string(body))
+	h.mu.Unlock()
+	w.Write([]byte("[]"))
+}
+
+func TestHeaderMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		mw     Middleware
+		header string
+		want   string
+	}{
+		{name: "user agent", mw: UserAgent("jello-test/1.0"), header: "User-Agent", want: "jello-test/1.0"},
+		{name: "api key", mw: APIKey("s3cret"), header: "X-API-Key", want: "s3cret"},
+		{name: "basic auth", mw: BasicAuth("frodo", "ring"), header: "Authorization", want: "Basic ZnJvZG86cmluZw=="},
+		{
+			name: "bearer token",
+			mw: BearerToken(func(context.Context) (string, error) {
+				return "tok", nil
+			}),
+			header: "Authorization", want: "Bearer tok",
+		},
+		{name: "default header", mw: DefaultHeaders(http.Header{"X-Team": {"blue"}}), header: "X-Team", want: "blue"},
+		{name: "default header keeps the request's", mw: DefaultHeaders(http.Header{"Accept": {"text/plain"}}), header: "Accept", want: "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &recordHeaders{}
+			c, _ := newTestClient(t, h, WithMiddleware(StageHeaders, tt.mw))
+			if _, err := c.Locations.List(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := h.headers[0].Get(tt.header); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBearerTokenError(t *testing.T) {
+	h := &recordHeaders{}
+	boom := errors.New("no token")
+	c, _ := newTestClient(t, h, WithMiddleware(StageAuth, BearerToken(func(context.Context) (string, error) {
+		return "", boom
+	})))
+	if _, err := c.Locations.List(context.Background()); !errors.Is(err, boom) {
+		t.Fatalf("error = %v, want %v", err, boom)
+	}
+	if len(h.headers) != 0 {
+		t.Error("request was sent without a token")
+	}
+}
+
+func TestStageOrder(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	c, _ := newTestClient(t, &recordHeaders{},
+		WithMiddleware(StageObserve, mark("observe")),
+		WithMiddleware(StageAuth, mark("auth")),
+		WithMiddleware(StageHeaders, mark("headers1"), mark("headers2")),
+		WithMiddleware(StageRetry, mark("retry")),
+		WithMiddleware(StageHeaders, mark("headers3")),
+	)
+	if _, err := c.Locations.List(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := "headers1 headers2 headers3 retry auth observe"
+	if got := strings.Join(order, " "); got != want {
+		t.Errorf("order = %s, want %s", got, want)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		idempotency  bool
+		statuses     []int
+		wantAttempts int
+		wantStatus   int
+	}{
+		{name: "success first time", method: "GET", statuses: []int{200}, wantAttempts: 1, wantStatus: 200},
+		{name: "503 then success", method: "GET", statuses: []int{503, 200}, wantAttempts: 2, wantStatus: 200},
+		{name: "gives up after max attempts", method: "GET", statuses: []int{502, 502, 502, 200}, wantAttempts: 3, wantStatus: 502},
+		{name: "429 is retried", method: "DELETE", statuses: []int{429, 200}, wantAttempts: 2, wantStatus: 200},
+		{name: "500 is not retried", method: "GET", statuses: []int{500, 200}, wantAttempts: 1, wantStatus: 500},
+		{name: "404 is not retried", method: "GET", statuses: []int{404, 200}, wantAttempts: 1, wantStatus: 404},
+		{name: "POST is not retried", method: "POST", body: `{"name":"x"}`, statuses: []int{503, 200}, wantAttempts: 1, wantStatus: 503},
+		{name: "POST with idempotency key is retried with its body", method: "POST", body: `{"name":"x"}`, idempotency: true, statuses: []int{503, 200}, wantAttempts: 2, wantStatus: 200},
+		{name: "PUT body is replayed", method: "PUT", body: `{"name":"y"}`, statuses: []int{504, 503, 200}, wantAttempts: 3, wantStatus: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				bodies   []string
+				ids      []string
+				attempts []int
+			)
+			srv := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				n := len(bodies)
+				bodies = append(bodies, string(body))
+				ids = append(ids, r.Header.Get(RequestIDHeader))
+				mu.Unlock()
+				w.WriteHeader(tt.statuses[n])
+			})
+			observe := func(next http.RoundTripper) http.RoundTripper {
+				return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					attempts = append(attempts, Attempt(req.Context()))
+					return next.RoundTrip(req)
+				})
+			}
+			c, _ := newTestClient(t, srv,
+				WithMiddleware(StageHeaders, RequestID()),
+				WithMiddleware(StageRetry, Retry(RetryPolicy{BaseDelay: time.Millisecond})),
+				WithMiddleware(StageObserve, observe),
+			)
+
+			var in any
+			if tt.body != "" {
+				in = bytesBody("application/json", []byte(tt.body))
+			}
+			u, _ := c.endpoint("locations")
+			req, err := c.newRequest(context.Background(), tt.method, u, in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.idempotency {
+				req.Header.Set("Idempotency-Key", "k1")
+			}
+			res, err := c.HTTPClient().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != tt.wantStatus || len(bodies) != tt.wantAttempts {
+				t.Fatalf("status %d after %d attempts, want %d after %d", res.StatusCode, len(bodies), tt.wantStatus, tt.wantAttempts)
+			}
+			for i := range bodies {
+				if bodies[i] != tt.body {
+					t.Errorf("attempt %d body = %q, want %q", i+1, bodies[i], tt.body)
+				}
+				if ids[i] == "" || ids[i] != ids[0] {
+					t.Errorf("attempt %d request id = %q, first was %q", i+1, ids[i], ids[0])
+				}
+				if attempts[i] != i+1 {
+					t.Errorf("Attempt() = %d on attempt %d", attempts[i], i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenCanceled(t *testing.T) {
+	calls := 0
+	c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}), WithMiddleware(StageRetry, Retry(RetryPolicy{MaxDelay: time.Minute})))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.Locations.List(ctx)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("error = %v, want ErrTimeout", err)
+	}
+	if calls != 1 || time.Since(start) > time.Second {
+		t.Errorf("%d calls in %s, want 1 call and to stop at the deadline", calls, time.Since(start))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second}
+	withRetryAfter := func(v string) *http.Response {
+		return &http.Response{Header: http.Header{"Retry-After": {v}}}
+	}
+	tests := []struct {
+		name     string
+		attempt  int
+		res      *http.Response
+		min, max time.Duration
+	}{
+		{name: "first retry", attempt: 1, min: 80 * time.Millisecond, max: 100 * time.Millisecond},
+		{name: "doubles", attempt: 3, min: 320 * time.Millisecond, max: 400 * time.Millisecond},
+		{name: "capped", attempt: 20, min: 4 * time.Second, max: 5 * time.Second},
+		{name: "retry-after seconds", attempt: 1, res: withRetryAfter("2"), min: 2 * time.Second, max: 2 * time.Second},
+		{name: "retry-after date", attempt: 1, res: withRetryAfter("Thu, 01 Jan 2026 00:00:03 GMT"), min: 3 * time.Second, max: 3 * time.Second},
+		{name: "retry-after date in the past", attempt: 1, res: withRetryAfter("Wed, 31 Dec 2025 23:00:00 GMT"), min: 0, max: 0},
+		{name: "retry-after over the cap", attempt: 1, res: withRetryAfter("3600"), min: 5 * time.Second, max: 5 * time.Second},
+		{name: "bad retry-after", attempt: 1, res: withRetryAfter("later"), min: 80 * time.Millisecond, max: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 20 {
+				if d := backoff(p, tt.attempt, tt.res, now); d < tt.min || d > tt.max {
+					t.Fatalf("backoff = %s, want between %s and %s", d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
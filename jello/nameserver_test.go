@@ -0,0 +1,146 @@
+package jello_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+	"github.com/JavierLU90/http_clients_go/jello/jellotest"
+)
+
+// newClosingServer starts a server that closes every connection, so
+// each request has to dial and resolve again. It returns the port.
+func newClosingServer(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Connection", "close")
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	return u.Port()
+}
+
+func TestResolverNameserver(t *testing.T) {
+	port := newClosingServer(t)
+	dns := jellotest.NewDNSServer(t)
+	for _, r := range []struct{ name, typ, value string }{
+		{"api.jello.test", "A", "127.0.0.1"},
+		{"www.jello.test", "CNAME", "api.jello.test"},
+	} {
+		if err := dns.Add(r.name, r.typ, r.value, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		host    string
+		wantErr string
+	}{
+		{host: "api.jello.test"},
+		{host: "www.jello.test"},
+		{host: "missing.jello.test", wantErr: "no such host"},
+	}
+	for _, tt := range tests {
+		c, err := jello.NewClient("http://api.jello.test", jello.WithResolver(jello.ResolverConfig{
+			Nameserver: dns.Addr,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := c.HTTPClient().Get("http://" + tt.host + ":" + port + "/")
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.host, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.host, err)
+			continue
+		}
+		res.Body.Close()
+	}
+}
+
+func TestResolverNameserverCache(t *testing.T) {
+	port := newClosingServer(t)
+	tests := []struct {
+		name     string
+		cacheTTL time.Duration
+		wantMore bool
+	}{
+		{name: "cached", cacheTTL: time.Hour},
+		{name: "no cache", wantMore: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dns := jellotest.NewDNSServer(t)
+			if err := dns.Add("api.jello.test", "A", "127.0.0.1", time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			c, err := jello.NewClient("http://api.jello.test", jello.WithResolver(jello.ResolverConfig{
+				Nameserver: dns.Addr,
+				CacheTTL:   tt.cacheTTL,
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			get := func() {
+				t.Helper()
+				res, err := c.HTTPClient().Get("http://api.jello.test:" + port + "/")
+				if err != nil {
+					t.Fatal(err)
+				}
+				res.Body.Close()
+			}
+			get()
+			first := dns.Queries()
+			get()
+			if more := dns.Queries() > first; more != tt.wantMore {
+				t.Errorf("queries went from %d to %d, want more: %v", first, dns.Queries(), tt.wantMore)
+			}
+		})
+	}
+}
+
+// TestResolverRecordTTL checks that a DoH answer is kept for its record
+// TTL even when CacheTTL is longer.
+func TestResolverRecordTTL(t *testing.T) {
+	port := newClosingServer(t)
+	dns := jellotest.NewDNSServer(t)
+	if err := dns.Add("api.jello.test", "A", "127.0.0.1", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	doh := jellotest.NewDoHServer(t, dns)
+	c, err := jello.NewClient("http://api.jello.test", jello.WithResolver(jello.ResolverConfig{
+		Resolver: &jello.DoHResolver{URL: doh.URL + "/dns-query", Client: doh.Client(), NoFallback: true},
+		CacheTTL: time.Hour,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func() {
+		t.Helper()
+		res, err := c.HTTPClient().Get("http://api.jello.test:" + port + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	get()
+	first := dns.Queries()
+	get()
+	if dns.Queries() != first {
+		t.Fatalf("queries went from %d to %d within the record ttl", first, dns.Queries())
+	}
+	time.Sleep(1100 * time.Millisecond)
+	get()
+	if dns.Queries() == first {
+		t.Error("answer was still cached after its record ttl")
+	}
+}
@@ -0,0 +1,63 @@
+package jello
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNoProxy(t *testing.T) {
+	tests := []struct {
+		noProxy string
+		url     string
+		want    bool
+	}{
+		{noProxy: "", url: "http://example.com", want: false},
+		{noProxy: "*", url: "http://example.com", want: true},
+		{noProxy: "example.com", url: "http://example.com", want: true},
+		{noProxy: "example.com", url: "http://api.example.com", want: true},
+		{noProxy: "example.com", url: "http://notexample.com", want: false},
+		{noProxy: "Example.COM", url: "http://EXAMPLE.com./", want: true},
+		{noProxy: ".example.com", url: "http://example.com", want: false},
+		{noProxy: ".example.com", url: "http://api.example.com", want: true},
+		{noProxy: "*.example.com", url: "http://example.com", want: false},
+		{noProxy: "*.example.com", url: "http://a.b.example.com", want: true},
+		{noProxy: "example.com:8080", url: "http://example.com:8080", want: true},
+		{noProxy: "example.com:8080", url: "http://example.com", want: false},
+		{noProxy: "example.com:443", url: "https://example.com", want: true},
+		{noProxy: "example.com:80", url: "https://example.com", want: false},
+		{noProxy: "10.0.0.1", url: "http://10.0.0.1:8080", want: true},
+		{noProxy: "10.0.0.1", url: "http://10.0.0.2", want: false},
+		{noProxy: "10.0.0.0/8", url: "http://10.20.30.40", want: true},
+		{noProxy: "10.0.0.0/8", url: "http://11.0.0.1", want: false},
+		{noProxy: "10.0.0.0/8", url: "http://10.example.com", want: false},
+		{noProxy: "::1", url: "http://[::1]:8080", want: true},
+		{noProxy: "[::1]:8080", url: "http://[::1]:8080", want: true},
+		{noProxy: "[::1]:8080", url: "http://[::1]:9090", want: false},
+		{noProxy: "fd00::/8", url: "http://[fd12::1]", want: true},
+		{noProxy: "127.0.0.1", url: "http://[::ffff:127.0.0.1]", want: true},
+		{noProxy: "localhost", url: "http://127.0.0.1", want: false},
+		{noProxy: "a.com, b.com ,,c.com", url: "http://b.com", want: true},
+	}
+	for _, tt := range tests {
+		l, err := parseNoProxy(tt.noProxy)
+		if err != nil {
+			t.Errorf("parseNoProxy(%q): %v", tt.noProxy, err)
+			continue
+		}
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := l.match(u); got != tt.want {
+			t.Errorf("NoProxy %q matches %s = %v, want %v", tt.noProxy, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestParseNoProxyErrors(t *testing.T) {
+	for _, s := range []string{"exa mple.com", "a/b", ".", "*.", "foo*.com"} {
+		if _, err := parseNoProxy(s); err == nil {
+			t.Errorf("parseNoProxy(%q) succeeded, want error", s)
+		}
+	}
+}
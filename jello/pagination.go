@@ -0,0 +1,243 @@
+package jello
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Query parameters and headers used for paging.
+const (
+	pageParam        = "page"
+	perPageParam     = "per_page"
+	cursorParam      = "cursor"
+	nextCursorHeader = "X-Next-Cursor"
+)
+
+// ListOptions controls how a list endpoint is paged.
+//
+// The server decides which paging style is used. After each page the
+// iterator looks, in order, for:
+//   - a Link header with rel="next" (RFC 8288), which is followed as is
+//   - a cursor token in the X-Next-Cursor header or a "next_cursor" field
+//     in the body, which is sent back as ?cursor=
+//   - a full page of PerPage items, after which ?page= is incremented
+//
+// If none of these apply, the current page was the last one. So is a
+// page without a link or cursor once the server has sent one, or once
+// the listing started from Cursor, even if it is full. A next link or
+// cursor that points back at the page just fetched is an error, since
+// following it would never end.
+type ListOptions struct {
+	// PerPage is sent as ?per_page=. 0 leaves it to the server.
+	PerPage int
+	// MaxItems stops the iteration after this many items. 0 means no limit.
+	MaxItems int
+	// Cursor starts the listing from a cursor returned earlier.
+	Cursor string
+}
+
+// paginate returns an iterator over every item of the list at u.
+// Iteration stops at the first error, which is yielded with a zero T.
+func paginate[T any](ctx context.Context, c *Client, u *url.URL, opts *ListOptions) iter.Seq2[T, error] {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	return func(yield func(T, error) bool) {
+		var zero T
+		next := firstPage(u, opts)
+		page := 1
+		seen := 0
+		// linked is set once the server pages with links or cursors,
+		// after which counting pages would start over from page 2.
+		linked := opts.Cursor != ""
+		for next != nil {
+			if err := ctx.Err(); err != nil {
+				yield(zero, newRequestError(http.MethodGet, next, err))
+				return
+			}
+
+			res, err := c.send(ctx, http.MethodGet, next, nil)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			items, cursor, err := decodePage[T](res)
+			drainAndClose(res.Body)
+			if isContextErr(err) {
+				err = newRequestError(http.MethodGet, next, err)
+			}
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range items {
+				if opts.MaxItems > 0 && seen >= opts.MaxItems {
+					return
+				}
+				if !yield(item, nil) {
+					return
+				}
+				seen++
+			}
+			if opts.MaxItems > 0 && seen >= opts.MaxItems {
+				return
+			}
+
+			switch link := nextLink(res.Header, next); {
+			case link != nil:
+				if link.Host != next.Host || link.Scheme != next.Scheme {
+					yield(zero, fmt.Errorf("next page link %q points to another origin", link.Redacted()))
+					return
+				}
+				if link.String() == next.String() {
+					yield(zero, fmt.Errorf("next page link %q points to the page just fetched", link.Redacted()))
+					return
+				}
+				next = link
+				linked = true
+			case cursor != "":
+				if cursor == next.Query().Get(cursorParam) {
+					yield(zero, fmt.Errorf("next page cursor %q is the one just fetched", cursor))
+					return
+				}
+				next = withQuery(next, func(q url.Values) {
+					q.Del(pageParam)
+					q.Set(cursorParam, cursor)
+				})
+				linked = true
+			case !linked && opts.PerPage > 0 && len(items) == opts.PerPage:
+				page++
+				next = withQuery(next, func(q url.Values) {
+					q.Set(pageParam, strconv.Itoa(page))
+				})
+			default:
+				next = nil
+			}
+		}
+	}
+}
+
+func firstPage(u *url.URL, opts *ListOptions) *url.URL {
+	return withQuery(u, func(q url.Values) {
+		if opts.PerPage > 0 {
+			q.Set(perPageParam, strconv.Itoa(opts.PerPage))
+		}
+		if opts.Cursor != "" {
+			q.Set(cursorParam, opts.Cursor)
+		}
+	})
+}
+
+// withQuery returns a copy of u with its query changed by edit.
+func withQuery(u *url.URL, edit func(url.Values)) *url.URL {
+	next := *u
+	q := next.Query()
+	edit(q)
+	next.RawQuery = q.Encode()
+	return &next
+}
+
+// decodePage reads a page that is either a bare JSON array or an object
+// of the form {"items": [...], "next_cursor": "..."}.
+func decodePage[T any](res *http.Response) ([]T, string, error) {
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading response: %w", err)
+	}
+	cursor := res.Header.Get(nextCursorHeader)
+
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var items []T
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, "", fmt.Errorf("error decoding response body: %w", err)
+		}
+		return items, cursor, nil
+	}
+
+	var envelope struct {
+		Items      []T    `json:"items"`
+		NextCursor string `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, "", fmt.Errorf("error decoding response body: %w", err)
+	}
+	if envelope.NextCursor != "" {
+		cursor = envelope.NextCursor
+	}
+	return envelope.Items, cursor, nil
+}
+
+// nextLink returns the target of the rel="next" link in header, resolved
+// against the request URL, or nil if there is none.
+func nextLink(header http.Header, base *url.URL) *url.URL {
+	for _, value := range header.Values("Link") {
+		for _, link := range splitLinks(value) {
+			target, params, ok := strings.Cut(link, ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			if !hasRel(params, "next") {
+				continue
+			}
+			u, err := base.Parse(target[1 : len(target)-1])
+			if err != nil {
+				continue
+			}
+			return u
+		}
+	}
+	return nil
+}
+
+// splitLinks splits a Link header value on the commas between links,
+// ignoring commas inside <...> and quoted strings.
+func splitLinks(value string) []string {
+	var links []string
+	inURI, inQuote := false, false
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '<' && !inQuote:
+			inURI = true
+		case c == '>' && !inQuote:
+			inURI = false
+		case c == '"' && !inURI:
+			inQuote = !inQuote
+		case c == '\\' && inQuote:
+			i++
+		case c == ',' && !inURI && !inQuote:
+			links = append(links, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(links, value[start:])
+}
+
+// hasRel reports whether the link params contain rel among the
+// space-separated relation types.
+func hasRel(params, rel string) bool {
+	for _, param := range strings.Split(params, ";") {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		for _, r := range strings.Fields(value) {
+			if strings.EqualFold(r, rel) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package jello
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// pagedProjects serves projects p1..pN in pages, using style to tell
+// the client where the next page is.
+func pagedProjects(n int, style string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		perPage, _ := strconv.Atoi(q.Get("per_page"))
+		if perPage == 0 {
+			perPage = 2
+		}
+		start := 0
+		switch {
+		case q.Get("cursor") != "":
+			start, _ = strconv.Atoi(q.Get("cursor"))
+		case q.Get("page") != "":
+			page, _ := strconv.Atoi(q.Get("page"))
+			start = (page - 1) * perPage
+		}
+		var items []Project
+		for i := start; i < min(start+perPage, n); i++ {
+			items = append(items, Project{Id: fmt.Sprintf("p%d", i+1), Name: "project"})
+		}
+		next := ""
+		if start+perPage < n {
+			next = strconv.Itoa(start + perPage)
+		}
+
+		switch style {
+		case "link":
+			if next != "" {
+				w.Header().Set("Link", fmt.Sprintf(`<https://example.com/other>; rel="prev", <%s?cursor=%s&per_page=%d>; rel="next"`, r.URL.Path, next, perPage))
+			}
+		case "header":
+			w.Header().Set(nextCursorHeader, next)
+		case "envelope":
+			json.NewEncoder(w).Encode(map[string]any{"items": items, "next_cursor": next})
+			return
+		case "same-link":
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, r.URL.RequestURI()))
+		case "same-cursor":
+			w.Header().Set(nextCursorHeader, "2")
+		case "foreign-link":
+			w.Header().Set("Link", `<https://evil.example.com/projects?page=2>; rel="next"`)
+		}
+		json.NewEncoder(w).Encode(items)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int
+		style    string
+		opts     *ListOptions
+		wantIds  int
+		wantReqs int
+		wantErr  bool
+	}{
+		{name: "single page", total: 2, style: "page", wantIds: 2, wantReqs: 1},
+		{name: "page numbers", total: 5, style: "page", opts: &ListOptions{PerPage: 2}, wantIds: 5, wantReqs: 3},
+		{name: "page numbers with an empty last page", total: 4, style: "page", opts: &ListOptions{PerPage: 2}, wantIds: 4, wantReqs: 3},
+		{name: "link header", total: 5, style: "link", wantIds: 5, wantReqs: 3},
+		{name: "link header with a full last page", total: 4, style: "link", opts: &ListOptions{PerPage: 2}, wantIds: 4, wantReqs: 2},
+		{name: "cursor header", total: 5, style: "header", wantIds: 5, wantReqs: 3},
+		{name: "cursor header with a full last page", total: 4, style: "header", opts: &ListOptions{PerPage: 2}, wantIds: 4, wantReqs: 2},
+		{name: "cursor in envelope", total: 5, style: "envelope", wantIds: 5, wantReqs: 3},
+		{name: "starting cursor", total: 5, style: "envelope", opts: &ListOptions{Cursor: "2"}, wantIds: 3, wantReqs: 2},
+		{name: "starting cursor with a full last page", total: 4, style: "envelope", opts: &ListOptions{PerPage: 2, Cursor: "2"}, wantIds: 2, wantReqs: 1},
+		{name: "max items", total: 9, style: "link", opts: &ListOptions{MaxItems: 3}, wantIds: 3, wantReqs: 2},
+		{name: "link to another origin", total: 3, style: "foreign-link", wantIds: 2, wantReqs: 1, wantErr: true},
+		{name: "link to the same page", total: 6, style: "same-link", wantIds: 2, wantReqs: 1, wantErr: true},
+		{name: "same cursor again", total: 6, style: "same-cursor", wantIds: 4, wantReqs: 2, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqs := 0
+			h := pagedProjects(tt.total, tt.style)
+			c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reqs++
+				h(w, r)
+			}))
+
+			var ids []string
+			var err error
+			for p, e := range c.Projects.List(context.Background(), tt.opts) {
+				if e != nil {
+					err = e
+					break
+				}
+				ids = append(ids, p.Id)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(ids) != tt.wantIds {
+				t.Errorf("got %d items %v, want %d", len(ids), ids, tt.wantIds)
+			}
+			for i, id := range ids {
+				if tt.opts != nil && tt.opts.Cursor != "" {
+					break
+				}
+				if want := fmt.Sprintf("p%d", i+1); id != want {
+					t.Errorf("item %d = %s, want %s", i, id, want)
+				}
+			}
+			if reqs != tt.wantReqs {
+				t.Errorf("made %d requests, want %d", reqs, tt.wantReqs)
+			}
+		})
+	}
+}
+
+func TestPaginateStopsWhenLoopBreaks(t *testing.T) {
+	reqs := 0
+	h := pagedProjects(10, "link")
+	c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs++
+		h(w, r)
+	}))
+	for _, err := range c.Projects.List(context.Background(), nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		break
+	}
+	if reqs != 1 {
+		t.Errorf("made %d requests after breaking on the first item, want 1", reqs)
+	}
+}
+
+func TestSplitLinks(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{`<https://a.example/x?page=2>; rel="next"`, "https://a.example/x?page=2"},
+		{`<https://a.example/1>; rel="prev", <https://a.example/3>; rel="next last"`, "https://a.example/3"},
+		{`<https://a.example/a,b>; title="x, y"; rel=next`, "https://a.example/a,b"},
+		{`<https://a.example/1>; rel="prev"`, ""},
+		{`https://a.example/2; rel="next"`, ""},
+	}
+	base, _ := http.NewRequest("GET", "https://a.example/x", nil)
+	for _, tt := range tests {
+		h := http.Header{"Link": {tt.header}}
+		got := ""
+		if u := nextLink(h, base.URL); u != nil {
+			got = u.String()
+		}
+		if got != tt.want {
+			t.Errorf("nextLink(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package jello_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+	"github.com/JavierLU90/http_clients_go/jello/jellotest"
+)
+
+func TestProxy(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "[]")
+	})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+	plainPort := plain.URL[len("http://127.0.0.1:"):]
+	securePort := secure.URL[len("https://127.0.0.1:"):]
+
+	tests := []struct {
+		name        string
+		proxy       jellotest.ProxyOptions
+		scheme      string // replaces the proxy URL's scheme, e.g. socks5h
+		password    string // replaces the password in the proxy URL
+		noProxy     string
+		base        string
+		wantTargets []string
+		wantErr     bool
+	}{
+		{name: "http", base: plain.URL, wantTargets: []string{"127.0.0.1:" + plainPort}},
+		{name: "https through connect", base: secure.URL, wantTargets: []string{"127.0.0.1:" + securePort}},
+		{
+			name:        "host name is sent to http proxy",
+			base:        "http://localhost:" + plainPort,
+			wantTargets: []string{"localhost:" + plainPort},
+		},
+		{
+			name:        "socks5 sends the host name",
+			proxy:       jellotest.ProxyOptions{SOCKS5: true},
+			base:        "http://localhost:" + plainPort,
+			wantTargets: []string{"localhost:" + plainPort},
+		},
+		{
+			name:        "socks5h",
+			proxy:       jellotest.ProxyOptions{SOCKS5: true},
+			scheme:      "socks5h",
+			base:        "http://localhost:" + plainPort,
+			wantTargets: []string{"localhost:" + plainPort},
+		},
+		{
+			name:        "socks5 https",
+			proxy:       jellotest.ProxyOptions{SOCKS5: true},
+			base:        secure.URL,
+			wantTargets: []string{"127.0.0.1:" + securePort},
+		},
+		{
+			name:        "http auth",
+			proxy:       jellotest.ProxyOptions{Username: "ada", Password: "secret"},
+			base:        plain.URL,
+			wantTargets: []string{"127.0.0.1:" + plainPort},
+		},
+		{
+			name:     "http wrong password",
+			proxy:    jellotest.ProxyOptions{Username: "ada", Password: "secret"},
+			password: "wrong",
+			base:     plain.URL,
+			wantErr:  true,
+		},
+		{
+			name:        "socks5 auth",
+			proxy:       jellotest.ProxyOptions{SOCKS5: true, Username: "ada", Password: "secret"},
+			base:        plain.URL,
+			wantTargets: []string{"127.0.0.1:" + plainPort},
+		},
+		{
+			name:     "socks5 wrong password",
+			proxy:    jellotest.ProxyOptions{SOCKS5: true, Username: "ada", Password: "secret"},
+			password: "wrong",
+			base:     plain.URL,
+			wantErr:  true,
+		},
+		{name: "no proxy", noProxy: "127.0.0.0/8", base: plain.URL},
+		{name: "no proxy other port", noProxy: "127.0.0.1:1", base: plain.URL, wantTargets: []string{"127.0.0.1:" + plainPort}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := jellotest.NewProxy(t, tt.proxy)
+			u := *proxy.URL
+			if tt.scheme != "" {
+				u.Scheme = tt.scheme
+			}
+			if tt.password != "" {
+				u.User = url.UserPassword(u.User.Username(), tt.password)
+			}
+			c, err := jello.NewClient(tt.base,
+				jello.WithProxy(jello.ProxyConfig{URL: u.String(), NoProxy: tt.noProxy}),
+				jello.WithTLSConfig(jello.TLSConfig{RootCAs: jellotest.CertPEM(secure.TLS.Certificates[0])}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.Locations.List(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := proxy.Targets(); !slices.Equal(got, tt.wantTargets) {
+				t.Errorf("proxy targets = %q, want %q", got, tt.wantTargets)
+			}
+		})
+	}
+}
+
+func TestRequestProxy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "[]")
+	}))
+	defer srv.Close()
+	clientProxy := jellotest.NewProxy(t, jellotest.ProxyOptions{})
+	otherProxy := jellotest.NewProxy(t, jellotest.ProxyOptions{SOCKS5: true})
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		wantClient int
+		wantOther  int
+	}{
+		{name: "client proxy", ctx: context.Background(), wantClient: 1},
+		{name: "request proxy", ctx: jello.WithRequestProxy(context.Background(), otherProxy.URL), wantOther: 1},
+		{name: "direct", ctx: jello.WithRequestProxy(context.Background(), nil)},
+	}
+	for _, tt := range tests {
+		c, err := jello.NewClient(srv.URL, jello.WithProxy(jello.ProxyConfig{URL: clientProxy.URL.String()}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		before, otherBefore := len(clientProxy.Targets()), len(otherProxy.Targets())
+		if _, err := c.Locations.List(tt.ctx); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		gotClient := len(clientProxy.Targets()) - before
+		gotOther := len(otherProxy.Targets()) - otherBefore
+		if gotClient != tt.wantClient || gotOther != tt.wantOther {
+			t.Errorf("%s: client proxy got %d, request proxy %d, want %d, %d",
+				tt.name, gotClient, gotOther, tt.wantClient, tt.wantOther)
+		}
+	}
+}
+
+func TestProxyConfigErrors(t *testing.T) {
+	tests := []jello.ProxyConfig{
+		{URL: "ftp://proxy.corp:21"},
+		{URL: "http://"},
+		{URL: "http://proxy.corp:3128", NoProxy: "bad/entry"},
+		{URL: "http://proxy.corp:3128", NoProxy: "*.exa mple.com"},
+	}
+	for _, cfg := range tests {
+		if _, err := jello.NewClient("https://api.jello.test", jello.WithProxy(cfg)); err == nil {
+			t.Errorf("NewClient with %+v succeeded, want error", cfg)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package jello
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when a request would have to wait for the
+// rate limiter past its context deadline, so it fails right away instead.
+var ErrRateLimited = errors.New("jello: rate limited")
+
+// Limit is a token bucket: Rate requests per second on average, with
+// bursts of up to Burst requests. A zero Rate means no limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Every returns a Limit of one request per interval with the given burst.
+func Every(interval time.Duration, burst int) Limit {
+	return Limit{Rate: float64(time.Second) / float64(interval), Burst: burst}
+}
+
+// RateLimiter throttles requests on the client side. Each host gets its
+// own token bucket and endpoints can have tighter ones of their own.
+// On top of that it follows the quota the server reports in
+// X-RateLimit-Remaining/X-RateLimit-Reset, RateLimit-Remaining/
+// RateLimit-Reset or RateLimit headers, and Retry-After on a 429.
+//
+// A request waits until every bucket that applies has a token. If its
+// context would expire before then, it fails with ErrRateLimited
+// without waiting.
+type RateLimiter struct {
+	mu        sync.Mutex
+	perHost   Limit
+	hosts     map[string]Limit
+	endpoints map[string][]endpointLimit
+	buckets   map[string]*bucket
+	quotas    map[string]*serverQuota
+}
+
+type endpointLimit struct {
+	prefix string
+	limit  Limit
+}
+
+// NewRateLimiter returns a limiter that applies perHost to every host
+// without a limit of its own.
+func NewRateLimiter(perHost Limit) *RateLimiter {
+	return &RateLimiter{
+		perHost:   perHost,
+		hosts:     map[string]Limit{},
+		endpoints: map[string][]endpointLimit{},
+		buckets:   map[string]*bucket{},
+		quotas:    map[string]*serverQuota{},
+	}
+}
+
+// SetHostLimit sets the limit for one host, e.g. "api.jello.com".
+func (l *RateLimiter) SetHostLimit(host string, limit Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hosts[host] = limit
+	delete(l.buckets, host)
+}
+
+// SetEndpointLimit sets an extra limit for requests to host whose path
+// starts with pathPrefix. When several prefixes match, the longest wins.
+func (l *RateLimiter) SetEndpointLimit(host, pathPrefix string, limit Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limits := l.endpoints[host]
+	for i := range limits {
+		if limits[i].prefix == pathPrefix {
+			limits[i].limit = limit
+			delete(l.buckets, host+pathPrefix)
+			return
+		}
+	}
+	l.endpoints[host] = append(limits, endpointLimit{prefix: pathPrefix, limit: limit})
+}
+
+// WithRateLimiter throttles every request made by the client with l.
+// A limiter can be shared between clients talking to the same API.
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(c *Client) {
+		c.rateLimiter = l
+	}
+}
+
+// Middleware wraps next so requests wait for the limiter and responses
+// update the server quota.
+func (l *RateLimiter) Middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if err := l.Wait(req.Context(), req); err != nil {
+			return nil, err
+		}
+		res, err := next.RoundTrip(req)
+		if err == nil {
+			l.Update(req.URL.Host, res)
+		}
+		return res, err
+	})
+}
+
+// Wait blocks until req may be sent. If ctx is done first, the tokens
+// reserved for req are given back.
+func (l *RateLimiter) Wait(ctx context.Context, req *http.Request) error {
+	now := time.Now()
+	r, wait, ok := l.reserve(ctx, req, now)
+	if !ok {
+		return fmt.Errorf("%w: would have to wait %s", ErrRateLimited, wait.Round(time.Millisecond))
+	}
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(r, time.Now())
+		return ctx.Err()
+	}
+}
+
+// reservation is what reserve took for a request.
+type reservation struct {
+	buckets []*bucket
+	quota   *serverQuota
+}
+
+// reserve takes a token from every bucket that applies to req and
+// returns how long to wait before sending it. If that is past the
+// context deadline nothing is taken and ok is false.
+func (l *RateLimiter) reserve(ctx context.Context, req *http.Request, now time.Time) (r reservation, wait time.Duration, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	host := req.URL.Host
+	if b := l.bucket(host, l.hostLimit(host)); b != nil {
+		r.buckets = append(r.buckets, b)
+	}
+	if e, ok := l.endpoint(host, req.URL.Path); ok {
+		if b := l.bucket(host+e.prefix, e.limit); b != nil {
+			r.buckets = append(r.buckets, b)
+		}
+	}
+
+	for _, b := range r.buckets {
+		wait = max(wait, b.delay(now))
+	}
+	q := l.quotas[host]
+	if q != nil && q.remaining <= 0 && now.Before(q.reset) {
+		wait = max(wait, q.reset.Sub(now))
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && now.Add(wait).After(deadline) {
+		return reservation{}, wait, false
+	}
+	for _, b := range r.buckets {
+		b.take(now)
+	}
+	if q != nil && q.remaining > 0 {
+		q.remaining--
+		r.quota = q
+	}
+	return r, wait, true
+}
+
+// cancel gives back what r took, like rate.Reservation.Cancel, so a
+// request that gave up waiting doesn't use up quota it never spent.
+func (l *RateLimiter) cancel(r reservation, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, b := range r.buckets {
+		b.refill(now)
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+1)
+	}
+	if r.quota != nil {
+		r.quota.remaining++
+	}
+}
+
+func (l *RateLimiter) hostLimit(host string) Limit {
+	if limit, ok := l.hosts[host]; ok {
+		return limit
+	}
+	return l.perHost
+}
+
+func (l *RateLimiter) endpoint(host, path string) (endpointLimit, bool) {
+	var best endpointLimit
+	found := false
+	for _, e := range l.endpoints[host] {
+		if strings.HasPrefix(path, e.prefix) && (!found || len(e.prefix) > len(best.prefix)) {
+			best, found = e, true
+		}
+	}
+	return best, found
+}
+
+func (l *RateLimiter) bucket(key string, limit Limit) *bucket {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newBucket(limit)
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// Update records the quota reported in res for host.
+func (l *RateLimiter) Update(host string, res *http.Response) {
+	remaining, reset, ok := parseQuota(res.Header, time.Now())
+	if res.StatusCode == http.StatusTooManyRequests {
+		if retry, ok2 := parseRetryAfter(res.Header.Get("Retry-After"), time.Now()); ok2 {
+			remaining, reset, ok = 0, retry, true
+		}
+	}
+	if !ok {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.quotas[host] = &serverQuota{remaining: remaining, reset: reset}
+}
+
+// serverQuota is what the server last said is left of its quota.
+type serverQuota struct {
+	remaining int
+	reset     time.Time
+}
+
+// bucket is a token bucket. Tokens can go negative, which means
+// requests are queued waiting for them.
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit Limit) *bucket {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &bucket{limit: limit, tokens: float64(limit.Burst)}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if !b.last.IsZero() {
+		elapsed := now.Sub(b.last).Seconds()
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+	}
+	b.last = now
+}
+
+// delay is how long until a token is available.
+func (b *bucket) delay(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+}
+
+func (b *bucket) take(now time.Time) {
+	b.refill(now)
+	b.tokens--
+}
+
+// parseQuota reads the remaining quota and its reset time from the
+// X-RateLimit-*, RateLimit-* or RateLimit headers.
+func parseQuota(h http.Header, now time.Time) (remaining int, reset time.Time, ok bool) {
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		r, err := strconv.Atoi(strings.TrimSpace(h.Get(prefix + "Remaining")))
+		if err != nil {
+			continue
+		}
+		return r, parseReset(h.Get(prefix+"Reset"), now), true
+	}
+	if v := h.Get("RateLimit"); v != "" {
+		return parseRateLimitHeader(v, now)
+	}
+	return 0, time.Time{}, false
+}
+
+// parseRateLimitHeader handles both forms used by the IETF draft:
+//
+//	RateLimit: limit=100, remaining=50, reset=5
+//	RateLimit: "default";r=50;t=5
+func parseRateLimitHeader(v string, now time.Time) (remaining int, reset time.Time, ok bool) {
+	fields := strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ';' })
+	for _, field := range fields {
+		key, value, found := strings.Cut(strings.TrimSpace(field), "=")
+		if !found {
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "remaining", "r":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return 0, time.Time{}, false
+			}
+			remaining, ok = n, true
+		case "reset", "t":
+			reset = parseReset(value, now)
+		}
+	}
+	return remaining, reset, ok
+}
+
+// parseReset reads a reset value that is either seconds from now or,
+// as some APIs send, a Unix timestamp.
+func parseReset(v string, now time.Time) time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n < 0 {
+		return time.Time{}
+	}
+	if n > 1_000_000_000 {
+		return time.Unix(n, 0)
+	}
+	return now.Add(time.Duration(n) * time.Second)
+}
+
+// parseRetryAfter reads Retry-After as seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, false
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		return now.Add(time.Duration(n) * time.Second), true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
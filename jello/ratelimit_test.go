@@ -0,0 +1,225 @@
+package jello
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newReq(t *testing.T, rawURL string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		setup func(l *RateLimiter)
+		urls  []string
+		// want is the wait for each request, rounded to milliseconds.
+		want []time.Duration
+	}{
+		{
+			name: "burst then one per interval",
+			urls: []string{"http://a/x", "http://a/x", "http://a/x", "http://a/x"},
+			want: []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name: "hosts have separate buckets",
+			urls: []string{"http://a/", "http://a/", "http://b/", "http://b/"},
+			want: []time.Duration{0, 0, 0, 0},
+		},
+		{
+			name:  "host limit replaces the default",
+			setup: func(l *RateLimiter) { l.SetHostLimit("a", Every(time.Second, 1)) },
+			urls:  []string{"http://a/", "http://a/"},
+			want:  []time.Duration{0, time.Second},
+		},
+		{
+			name:  "endpoint limit on top of the host",
+			setup: func(l *RateLimiter) { l.SetEndpointLimit("a", "/search", Every(time.Second, 1)) },
+			urls:  []string{"http://a/search?q=1", "http://a/issues", "http://a/search?q=2"},
+			want:  []time.Duration{0, 0, time.Second},
+		},
+		{
+			name: "longest endpoint prefix wins",
+			setup: func(l *RateLimiter) {
+				l.SetEndpointLimit("a", "/v1", Every(time.Second, 1))
+				l.SetEndpointLimit("a", "/v1/search", Every(2*time.Second, 5))
+			},
+			// The third waits for the host bucket, not the 1s of /v1.
+			urls: []string{"http://a/v1/search", "http://a/v1/other", "http://a/v1/search"},
+			want: []time.Duration{0, 0, 100 * time.Millisecond},
+		},
+		{
+			name: "server quota exhausted",
+			setup: func(l *RateLimiter) {
+				l.quotas["a"] = &serverQuota{remaining: 1, reset: now.Add(3 * time.Second)}
+			},
+			urls: []string{"http://a/", "http://a/"},
+			want: []time.Duration{0, 3 * time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewRateLimiter(Every(100*time.Millisecond, 2))
+			if tt.setup != nil {
+				tt.setup(l)
+			}
+			for i, u := range tt.urls {
+				// Reserve from the same instant, so these are the
+				// delays and not how long the test took.
+				_, wait, ok := l.reserve(context.Background(), newReq(t, u), now)
+				if !ok {
+					t.Fatalf("request %d: not reserved", i)
+				}
+				if got := wait.Round(time.Millisecond); got != tt.want[i] {
+					t.Errorf("request %d to %s waits %s, want %s", i, u, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterDeadline(t *testing.T) {
+	l := NewRateLimiter(Every(time.Second, 1))
+	req := newReq(t, "http://a/")
+	if err := l.Wait(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := l.Wait(ctx, req)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("error = %v, want ErrRateLimited", err)
+	}
+	if time.Since(start) > 40*time.Millisecond {
+		t.Errorf("waited %s before failing", time.Since(start))
+	}
+	// Nothing was taken, so the next request still waits about a second.
+	_, wait, _ := l.reserve(context.Background(), req, time.Now())
+	if wait > time.Second || wait < 900*time.Millisecond {
+		t.Errorf("wait after a rejected request = %s, want about 1s", wait)
+	}
+}
+
+func TestRateLimiterCancelGivesTokensBack(t *testing.T) {
+	l := NewRateLimiter(Every(time.Second, 1))
+	l.quotas["a"] = &serverQuota{remaining: 5, reset: time.Now().Add(time.Minute)}
+	req := newReq(t, "http://a/")
+	if err := l.Wait(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	// Several callers give up while waiting for the next token.
+	for range 3 {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		if err := l.Wait(ctx, req); !errors.Is(err, context.Canceled) {
+			t.Fatalf("error = %v, want context.Canceled", err)
+		}
+	}
+
+	_, wait, _ := l.reserve(context.Background(), req, time.Now())
+	if wait > time.Second {
+		t.Errorf("wait after canceled waiters = %s, want at most 1s", wait)
+	}
+	if got := l.quotas["a"].remaining; got != 3 {
+		t.Errorf("server quota remaining = %d, want 3", got)
+	}
+}
+
+func TestRateLimiterFollowsServer(t *testing.T) {
+	var calls atomic.Int32
+	c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("[]"))
+	}), WithRateLimiter(NewRateLimiter(Limit{})))
+
+	if _, err := c.Locations.List(context.Background()); err == nil {
+		t.Fatal("first call succeeded, want the 429")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := c.Locations.List(ctx); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("call during Retry-After: error = %v, want ErrRateLimited", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("server got %d calls, want 1", calls.Load())
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		header        http.Header
+		wantRemaining int
+		wantReset     time.Time
+		wantOK        bool
+	}{
+		{
+			name:          "x-ratelimit with seconds",
+			header:        http.Header{"X-Ratelimit-Remaining": {"7"}, "X-Ratelimit-Reset": {"30"}},
+			wantRemaining: 7, wantReset: now.Add(30 * time.Second), wantOK: true,
+		},
+		{
+			name:          "ratelimit with unix time",
+			header:        http.Header{"Ratelimit-Remaining": {"0"}, "Ratelimit-Reset": {"1767225660"}},
+			wantRemaining: 0, wantReset: time.Unix(1767225660, 0), wantOK: true,
+		},
+		{
+			name:          "combined header",
+			header:        http.Header{"Ratelimit": {"limit=100, remaining=50, reset=5"}},
+			wantRemaining: 50, wantReset: now.Add(5 * time.Second), wantOK: true,
+		},
+		{
+			name:          "structured header",
+			header:        http.Header{"Ratelimit": {`"default";r=3;t=10`}},
+			wantRemaining: 3, wantReset: now.Add(10 * time.Second), wantOK: true,
+		},
+		{name: "none", header: http.Header{}},
+		{name: "garbage", header: http.Header{"X-Ratelimit-Remaining": {"lots"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining, reset, ok := parseQuota(tt.header, now)
+			if ok != tt.wantOK || remaining != tt.wantRemaining || !reset.Equal(tt.wantReset) {
+				t.Errorf("parseQuota = %d, %s, %v; want %d, %s, %v", remaining, reset, ok, tt.wantRemaining, tt.wantReset, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in     string
+		want   time.Time
+		wantOK bool
+	}{
+		{"120", now.Add(2 * time.Minute), true},
+		{"Thu, 01 Jan 2026 00:00:30 GMT", now.Add(30 * time.Second), true},
+		{"", time.Time{}, false},
+		{"soon", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.in, now)
+		if ok != tt.wantOK || !got.Equal(tt.want) {
+			t.Errorf("parseRetryAfter(%q) = %s, %v; want %s, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
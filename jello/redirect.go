@@ -0,0 +1,188 @@
+package jello
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// RedirectMode says which redirects a client follows.
+type RedirectMode int
+
+const (
+	// RedirectFollow follows redirects to any host.
+	RedirectFollow RedirectMode = iota
+	// RedirectSameHost follows redirects to the same host name only,
+	// and never from https to http.
+	RedirectSameHost
+	// RedirectNever returns the 3xx response instead of following it.
+	// Through the services it becomes an APIError.
+	RedirectNever
+)
+
+// DefaultMaxRedirects matches the limit http.Client uses.
+const DefaultMaxRedirects = 10
+
+// NoRedirects as RedirectPolicy.MaxRedirects fails any request that is
+// redirected with ErrTooManyRedirects, like curl --max-redirs 0. Use
+// RedirectNever to get the 3xx response back instead.
+const NoRedirects = -1
+
+// DefaultSensitiveHeaders are removed when a redirect leaves the
+// origin of the first request. With a cookie jar, the Cookie header is
+// then set again from the jar's cookies for the new URL.
+var DefaultSensitiveHeaders = []string{"Authorization", "X-API-Key", "Cookie"}
+
+var (
+	// ErrTooManyRedirects matches a RedirectError for a request that was
+	// redirected more than RedirectPolicy.MaxRedirects times.
+	ErrTooManyRedirects = errors.New("jello: too many redirects")
+
+	// ErrRedirectNotAllowed matches a RedirectError for a redirect the
+	// policy refused, such as one to another host with RedirectSameHost.
+	ErrRedirectNotAllowed = errors.New("jello: redirect not allowed")
+)
+
+// RedirectPolicy controls how a client follows redirects.
+//
+// Redirects with 307 and 308 keep the method and body; 301, 302 and 303
+// turn into a GET without a body, as browsers do. Request bodies from
+// the services can always be sent again.
+type RedirectPolicy struct {
+	Mode RedirectMode
+	// MaxRedirects is how many redirects a request may follow. 0
+	// defaults to DefaultMaxRedirects; NoRedirects allows none.
+	MaxRedirects int
+	// SensitiveHeaders are removed from requests redirected to a
+	// different origin (scheme, host and port) than the first request,
+	// including headers added by middleware like APIKey. Defaults to
+	// DefaultSensitiveHeaders; set an empty, non-nil slice to keep them.
+	SensitiveHeaders []string
+}
+
+// WithRedirectPolicy sets how redirects are followed. Without it, the
+// client follows up to DefaultMaxRedirects to any host, or keeps the
+// CheckRedirect of a client given to WithHTTPClient.
+func WithRedirectPolicy(p RedirectPolicy) Option {
+	return func(c *Client) {
+		c.redirects = &p
+	}
+}
+
+// RedirectError is a redirect the client didn't follow.
+type RedirectError struct {
+	// URL is where the refused redirect pointed.
+	URL string
+	// Chain is the URLs requested before it, oldest first.
+	Chain []string
+	// Reason is ErrTooManyRedirects or ErrRedirectNotAllowed.
+	Reason error
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("redirect to %s: %v", e.URL, e.Reason)
+}
+
+func (e *RedirectError) Unwrap() error {
+	return e.Reason
+}
+
+// checkRedirect is the http.Client CheckRedirect for p. via holds the
+// requests made so far, so len(via) redirects have been followed once
+// req is sent.
+func (p RedirectPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if p.Mode == RedirectNever {
+		return http.ErrUseLastResponse
+	}
+	refuse := func(reason error) error {
+		chain := make([]string, len(via))
+		for i, r := range via {
+			chain[i] = r.URL.Redacted()
+		}
+		return &RedirectError{URL: req.URL.Redacted(), Chain: chain, Reason: reason}
+	}
+
+	limit := p.MaxRedirects
+	switch {
+	case limit == NoRedirects:
+		limit = 0
+	case limit <= 0:
+		limit = DefaultMaxRedirects
+	}
+	if len(via) > limit {
+		return refuse(ErrTooManyRedirects)
+	}
+	if p.Mode == RedirectSameHost {
+		prev := via[len(via)-1].URL
+		if !strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) ||
+			prev.Scheme == "https" && req.URL.Scheme != "https" {
+			return refuse(ErrRedirectNotAllowed)
+		}
+	}
+	return nil
+}
+
+// stripSensitive removes headers from requests that were redirected
+// away from the origin of the first one. It runs inside the middleware
+// so headers that middleware added are caught too. Cookies the jar has
+// for the new URL are put back, since they belong there.
+func stripSensitive(headers []string, jar http.CookieJar) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Response == nil || len(headers) == 0 {
+				return next.RoundTrip(req)
+			}
+			first := req
+			for first.Response != nil && first.Response.Request != nil {
+				first = first.Response.Request
+			}
+			if sameOrigin(first.URL, req.URL) {
+				return next.RoundTrip(req)
+			}
+			req = req.Clone(req.Context())
+			for _, h := range headers {
+				req.Header.Del(h)
+			}
+			if jar != nil && req.Header.Get("Cookie") == "" {
+				for _, c := range jar.Cookies(req.URL) {
+					req.AddCookie(c)
+				}
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func sameOrigin(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme && strings.EqualFold(a.Hostname(), b.Hostname()) && defaultPort(a) == defaultPort(b)
+}
+
+// defaultPort returns u's port, or the default one for its scheme.
+func defaultPort(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	if u.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
+
+// RedirectChain returns the URLs requested to get res, oldest first,
+// ending with res.Request.URL. It has one entry if there were no
+// redirects.
+func RedirectChain(res *http.Response) []*url.URL {
+	var chain []*url.URL
+	for req := res.Request; req != nil; {
+		chain = append(chain, req.URL)
+		if req.Response == nil {
+			break
+		}
+		req = req.Response.Request
+	}
+	slices.Reverse(chain)
+	return chain
+}
@@ -0,0 +1,288 @@
+package jello
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// redirectLoop redirects /hop/n to /hop/n+1 until n reaches its limit.
+func redirectLoop(limit int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+		if n >= limit {
+			w.Write([]byte("[]"))
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/hop/%d", n+1), http.StatusFound)
+	}
+}
+
+func TestMaxRedirects(t *testing.T) {
+	tests := []struct {
+		name    string
+		max     int
+		hops    int
+		wantErr error
+	}{
+		{name: "no redirects needed", max: NoRedirects, hops: 0},
+		{name: "NoRedirects refuses the first", max: NoRedirects, hops: 1, wantErr: ErrTooManyRedirects},
+		{name: "zero means the default", max: 0, hops: DefaultMaxRedirects},
+		{name: "over the default", max: 0, hops: DefaultMaxRedirects + 1, wantErr: ErrTooManyRedirects},
+		{name: "at the limit", max: 3, hops: 3},
+		{name: "over the limit", max: 3, hops: 4, wantErr: ErrTooManyRedirects},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, srv := newTestClient(t, redirectLoop(tt.hops), WithRedirectPolicy(RedirectPolicy{MaxRedirects: tt.max}))
+			req, _ := http.NewRequestWithContext(context.Background(), "GET", srv.URL+"/hop/0", nil)
+			err := c.doRequest(req, nil)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				return
+			}
+			// The chain holds the first request and every redirect
+			// followed before the refused one.
+			followed := tt.max
+			switch tt.max {
+			case 0:
+				followed = DefaultMaxRedirects
+			case NoRedirects:
+				followed = 0
+			}
+			var redirErr *RedirectError
+			if !errors.As(err, &redirErr) || len(redirErr.Chain) != followed+1 {
+				t.Errorf("error = %#v, want a RedirectError with %d requests in the chain", err, followed+1)
+			}
+		})
+	}
+}
+
+// redirectServer serves /start, which redirects with status to target
+// (an absolute URL, or a path on the same server), and /end, which
+// reports the method, body and headers it got.
+type redirectServer struct {
+	status int
+	target func(r *http.Request) string
+}
+
+func (s redirectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/start":
+		w.Header().Set("Location", s.target(r))
+		w.WriteHeader(s.status)
+	case "/end":
+		body, _ := io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(map[string]string{
+			"method":  r.Method,
+			"body":    string(body),
+			"key":     r.Header.Get("X-API-Key"),
+			"auth":    r.Header.Get("Authorization"),
+			"cookies": r.Header.Get("Cookie"),
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestRedirectSensitiveHeaders(t *testing.T) {
+	other := httptest.NewServer(redirectServer{})
+	defer other.Close()
+
+	tests := []struct {
+		name      string
+		target    string // {port} is the first server's port, {other} the second's
+		sensitive []string
+		want      map[string]string
+	}{
+		{
+			name:   "same origin keeps everything",
+			target: "http://api.jello.test:{port}/end",
+			want:   map[string]string{"key": "k", "auth": "Basic YWRhOnB3", "cookies": "api=1; shared=2"},
+		},
+		{
+			name:   "other host gets its own jar cookies",
+			target: "http://files.jello.test:{port}/end",
+			want:   map[string]string{"key": "", "auth": "", "cookies": "shared=2; files=3"},
+		},
+		{
+			name:   "other port is another origin",
+			target: "http://api.jello.test:{other}/end",
+			want:   map[string]string{"key": "", "auth": "", "cookies": "api=1; shared=2"},
+		},
+		{
+			name:      "sensitive headers turned off",
+			target:    "http://files.jello.test:{port}/end",
+			sensitive: []string{},
+			want:      map[string]string{"key": "k", "auth": "Basic YWRhOnB3", "cookies": "shared=2; files=3"},
+		},
+		{
+			name:      "only the API key",
+			target:    "http://files.jello.test:{port}/end",
+			sensitive: []string{"X-API-Key"},
+			want:      map[string]string{"key": "", "auth": "Basic YWRhOnB3", "cookies": "shared=2; files=3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var target string
+			srv := httptest.NewServer(redirectServer{status: http.StatusFound, target: func(*http.Request) string {
+				return target
+			}})
+			defer srv.Close()
+			port := srv.URL[strings.LastIndex(srv.URL, ":")+1:]
+			target = strings.NewReplacer("{port}", port, "{other}", other.URL[strings.LastIndex(other.URL, ":")+1:]).Replace(tt.target)
+
+			jar, err := NewCookieJar(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			jar.SetCookies(mustURL(t, "http://api.jello.test/"), []*http.Cookie{
+				{Name: "api", Value: "1"},
+				{Name: "shared", Value: "2", Domain: "jello.test"},
+			})
+			jar.SetCookies(mustURL(t, "http://files.jello.test/"), []*http.Cookie{{Name: "files", Value: "3"}})
+
+			c, err := NewClient("http://api.jello.test:"+port,
+				WithResolver(ResolverConfig{Overrides: map[string][]string{
+					"api.jello.test":   {"127.0.0.1"},
+					"files.jello.test": {"127.0.0.1"},
+				}}),
+				WithCookieJar(jar),
+				WithAPIKey("k"),
+				WithMiddleware(StageAuth, BasicAuth("ada", "pw")),
+				WithRedirectPolicy(RedirectPolicy{SensitiveHeaders: tt.sensitive}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, _ := c.endpoint("start")
+			var got map[string]string
+			if err := c.do(context.Background(), http.MethodGet, u, nil, &got); err != nil {
+				t.Fatal(err)
+			}
+			for k, want := range tt.want {
+				if got[k] != want {
+					t.Errorf("%s = %q, want %q", k, got[k], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRedirectMethods(t *testing.T) {
+	tests := []struct {
+		status     int
+		wantMethod string
+		wantBody   string
+	}{
+		{status: http.StatusMovedPermanently, wantMethod: http.MethodGet},
+		{status: http.StatusFound, wantMethod: http.MethodGet},
+		{status: http.StatusSeeOther, wantMethod: http.MethodGet},
+		{status: http.StatusTemporaryRedirect, wantMethod: http.MethodPost, wantBody: `{"name":"x"}`},
+		{status: http.StatusPermanentRedirect, wantMethod: http.MethodPost, wantBody: `{"name":"x"}`},
+	}
+	for _, tt := range tests {
+		h := redirectServer{status: tt.status, target: func(*http.Request) string { return "/end" }}
+		c, srv := newTestClient(t, h)
+		var got map[string]string
+		u := mustURL(t, srv.URL+"/start")
+		if err := c.do(context.Background(), http.MethodPost, u, map[string]string{"name": "x"}, &got); err != nil {
+			t.Errorf("%d: %v", tt.status, err)
+			continue
+		}
+		if got["method"] != tt.wantMethod || strings.TrimSpace(got["body"]) != tt.wantBody {
+			t.Errorf("%d: got %s %q, want %s %q", tt.status, got["method"], got["body"], tt.wantMethod, tt.wantBody)
+		}
+	}
+}
+
+func TestRedirectModes(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       RedirectMode
+		target     func(plain string) string
+		wantErr    error
+		wantStatus int
+	}{
+		{name: "follow same host", mode: RedirectFollow, target: func(string) string { return "/end" }},
+		{name: "follow other host", mode: RedirectFollow, target: func(plain string) string {
+			return strings.Replace(plain, "127.0.0.1", "localhost", 1) + "/end"
+		}},
+		{name: "same host", mode: RedirectSameHost, target: func(string) string { return "/end" }},
+		{name: "same host refuses other host", mode: RedirectSameHost, wantErr: ErrRedirectNotAllowed, target: func(plain string) string {
+			return strings.Replace(plain, "127.0.0.1", "localhost", 1) + "/end"
+		}},
+		{name: "never", mode: RedirectNever, wantStatus: http.StatusFound, target: func(string) string { return "/end" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var base string
+			c, srv := newTestClient(t, redirectServer{status: http.StatusFound, target: func(*http.Request) string {
+				return tt.target(base)
+			}}, WithRedirectPolicy(RedirectPolicy{Mode: tt.mode}))
+			base = srv.URL
+			err := c.do(context.Background(), http.MethodGet, mustURL(t, srv.URL+"/start"), nil, nil)
+			var apiErr *APIError
+			switch {
+			case tt.wantStatus != 0:
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.wantStatus {
+					t.Errorf("error = %v, want status %d", err, tt.wantStatus)
+				}
+			case !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil):
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// RedirectSameHost never goes from https to http, even on one host.
+	plain := httptest.NewServer(redirectServer{})
+	defer plain.Close()
+	secure := httptest.NewTLSServer(redirectServer{status: http.StatusFound, target: func(*http.Request) string {
+		return plain.URL + "/end"
+	}})
+	defer secure.Close()
+	c, err := NewClient(secure.URL,
+		WithTLSConfig(TLSConfig{RootCAs: certPEM(secure)}),
+		WithRedirectPolicy(RedirectPolicy{Mode: RedirectSameHost}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.do(context.Background(), http.MethodGet, mustURL(t, secure.URL+"/start"), nil, nil)
+	if !errors.Is(err, ErrRedirectNotAllowed) {
+		t.Errorf("https to http: error = %v, want %v", err, ErrRedirectNotAllowed)
+	}
+}
+
+// certPEM returns the certificate of an httptest TLS server as PEM.
+func certPEM(srv *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+}
+
+func TestRedirectChain(t *testing.T) {
+	_, srv := newTestClient(t, redirectLoop(3))
+	res, err := http.Get(srv.URL + "/hop/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	var got []string
+	for _, u := range RedirectChain(res) {
+		got = append(got, u.Path)
+	}
+	want := []string{"/hop/0", "/hop/1", "/hop/2", "/hop/3"}
+	if !slices.Equal(got, want) {
+		t.Errorf("chain = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,221 @@
+package jello
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/JavierLU90/http_clients_go/uritemplate"
+)
+
+// RequestBuilder builds a request to the API a piece at a time, so URLs
+// never have to be put together by hand:
+//
+//	req, err := client.NewRequest().
+//		Method(http.MethodPatch).
+//		Path("issues", id).
+//		Query("notify", "true").
+//		JSON(update).
+//		Build()
+//
+// The first error is kept and returned by Build or Do.
+type RequestBuilder struct {
+	client   *Client
+	ctx      context.Context
+	method   string
+	segments []string
+	query    url.Values
+	header   http.Header
+	body     *RequestBody
+	err      error
+}
+
+// NewRequest starts building a GET request to the client's base URL.
+func (c *Client) NewRequest() *RequestBuilder {
+	return &RequestBuilder{
+		client: c,
+		ctx:    context.Background(),
+		method: http.MethodGet,
+		query:  url.Values{},
+		header: http.Header{},
+	}
+}
+
+// Context sets the request context.
+func (b *RequestBuilder) Context(ctx context.Context) *RequestBuilder {
+	b.ctx = ctx
+	return b
+}
+
+// Method sets the request method.
+func (b *RequestBuilder) Method(method string) *RequestBuilder {
+	b.method = method
+	return b
+}
+
+// Path appends path segments. Each one is escaped on its own, so "a/b"
+// stays a single segment. Segments can be strings, numbers or anything
+// else fmt prints, such as a LocationID; values with a Validate method
+// are validated first. A nil segment is an error.
+func (b *RequestBuilder) Path(segments ...any) *RequestBuilder {
+	for _, s := range segments {
+		if isNil(s) {
+			if b.err == nil {
+				b.err = fmt.Errorf("invalid path segment: nil")
+			}
+			continue
+		}
+		if v, ok := s.(interface{ Validate() error }); ok && b.err == nil {
+			b.err = v.Validate()
+		}
+		b.segments = append(b.segments, fmt.Sprint(s))
+	}
+	return b
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// Template appends the path that t expands to, and adds any query
+// parameters it expands to, such as from {?state}. Endpoints can be
+// declared once:
+//
+//	var locationPath = uritemplate.MustParse("/v{version}/courses_rest_api/learn-http/locations/{id}")
+//
+//	client.NewRequest().Template(locationPath, uritemplate.Values{"version": 1, "id": id})
+//
+// Like Path, the expansion may not contain empty, "." or ".." segments,
+// so an undefined variable can't quietly point the request elsewhere.
+func (b *RequestBuilder) Template(t *uritemplate.Template, vars uritemplate.Values) *RequestBuilder {
+	if b.err != nil {
+		return b
+	}
+	expanded, err := t.Expand(vars)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	ref, err := url.Parse(expanded)
+	if err != nil {
+		b.err = fmt.Errorf("error parsing expanded template %q: %w", expanded, err)
+		return b
+	}
+	if ref.Scheme != "" || ref.Host != "" {
+		b.err = fmt.Errorf("template %q must expand to a path, got %q", t, expanded)
+		return b
+	}
+	if path := strings.TrimPrefix(ref.EscapedPath(), "/"); path != "" {
+		for _, s := range strings.Split(path, "/") {
+			segment, err := url.PathUnescape(s)
+			if err != nil {
+				b.err = fmt.Errorf("error parsing expanded template %q: %w", expanded, err)
+				return b
+			}
+			b.segments = append(b.segments, segment)
+		}
+	}
+	for key, values := range ref.Query() {
+		b.query[key] = append(b.query[key], values...)
+	}
+	return b
+}
+
+// Query adds values for a query parameter. Calling it again with the
+// same key adds to the values instead of replacing them, for APIs that
+// take ?tag=a&tag=b.
+func (b *RequestBuilder) Query(key string, values ...string) *RequestBuilder {
+	if len(values) == 0 {
+		b.query.Add(key, "")
+	}
+	for _, v := range values {
+		b.query.Add(key, v)
+	}
+	return b
+}
+
+// Header adds a header value. The values given for a header replace
+// any the client sets itself, such as Accept or Content-Type; calling
+// Header again with the same key adds another value.
+func (b *RequestBuilder) Header(key, value string) *RequestBuilder {
+	b.header.Add(key, value)
+	return b
+}
+
+// JSON sets v, encoded as JSON, as the body.
+func (b *RequestBuilder) JSON(v any) *RequestBuilder {
+	body, err := JSONBody(v)
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+	b.body = body
+	return b
+}
+
+// Form sets values as a URL-encoded form body.
+func (b *RequestBuilder) Form(values url.Values) *RequestBuilder {
+	b.body = FormBody(values)
+	return b
+}
+
+// Body sets the body, e.g. from Multipart.Body.
+func (b *RequestBuilder) Body(body *RequestBody) *RequestBuilder {
+	b.body = body
+	return b
+}
+
+// URL returns the URL the request will be sent to.
+func (b *RequestBuilder) URL() (*url.URL, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	u, err := b.client.endpoint(b.segments...)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = b.query.Encode()
+	return u, nil
+}
+
+// Build returns the request, for inspecting or sending with
+// Client.HTTPClient. Do sends it with the client's timeout and error
+// handling instead.
+func (b *RequestBuilder) Build() (*http.Request, error) {
+	u, err := b.URL()
+	if err != nil {
+		return nil, err
+	}
+	var in any
+	if b.body != nil {
+		in = b.body
+	}
+	req, err := b.client.newRequest(b.ctx, b.method, u, in)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range b.header {
+		req.Header[key] = slices.Clone(values)
+	}
+	return req, nil
+}
+
+// Do sends the request and decodes the JSON response into out, if out
+// is not nil. Errors are the same as from the services.
+func (b *RequestBuilder) Do(out any) error {
+	req, err := b.Build()
+	if err != nil {
+		return err
+	}
+	return b.client.doRequest(req, out)
+}
@@ -0,0 +1,182 @@
+package jello
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/JavierLU90/http_clients_go/uritemplate"
+)
+
+func TestRequestBuilderURL(t *testing.T) {
+	c, err := NewClient("https://api.example.com/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nilID *LocationID
+	tests := []struct {
+		name    string
+		build   func(b *RequestBuilder) *RequestBuilder
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "base url",
+			build: func(b *RequestBuilder) *RequestBuilder { return b },
+			want:  "https://api.example.com/v1",
+		},
+		{
+			name:  "segments are escaped on their own",
+			build: func(b *RequestBuilder) *RequestBuilder { return b.Path("issues", "a/b c?d#e%f") },
+			want:  "https://api.example.com/v1/issues/a%2Fb%20c%3Fd%23e%25f",
+		},
+		{
+			name:  "numbers and ids",
+			build: func(b *RequestBuilder) *RequestBuilder { return b.Path("boards", 42, "locations", testLocationID) },
+			want:  "https://api.example.com/v1/boards/42/locations/" + testLocationID.String(),
+		},
+		{
+			name:  "unicode",
+			build: func(b *RequestBuilder) *RequestBuilder { return b.Path("projects", "café") },
+			want:  "https://api.example.com/v1/projects/caf%C3%A9",
+		},
+		{
+			name: "query values are escaped",
+			build: func(b *RequestBuilder) *RequestBuilder {
+				return b.Query("q", "a&b=c d+e").Query("path", "/x?y")
+			},
+			want: "https://api.example.com/v1?path=%2Fx%3Fy&q=a%26b%3Dc+d%2Be",
+		},
+		{
+			name: "repeated query keys",
+			build: func(b *RequestBuilder) *RequestBuilder {
+				return b.Query("tag", "a", "b").Query("tag", "c").Query("flag")
+			},
+			want: "https://api.example.com/v1?flag=&tag=a&tag=b&tag=c",
+		},
+		{
+			name: "template",
+			build: func(b *RequestBuilder) *RequestBuilder {
+				return b.Template(uritemplate.MustParse("/issues/{id}{?state,tag*}"), uritemplate.Values{
+					"id":    "a/b",
+					"state": "open now",
+					"tag":   []string{"x", "y"},
+				}).Query("tag", "z")
+			},
+			want: "https://api.example.com/v1/issues/a%2Fb?state=open+now&tag=x&tag=y&tag=z",
+		},
+		{
+			name:    "invalid id",
+			build:   func(b *RequestBuilder) *RequestBuilder { return b.Path("locations", LocationID("../admin")) },
+			wantErr: ErrInvalidID,
+		},
+		{name: "nil", build: func(b *RequestBuilder) *RequestBuilder { return b.Path("locations", nil) }},
+		{name: "nil pointer", build: func(b *RequestBuilder) *RequestBuilder { return b.Path("locations", nilID) }},
+		{name: "dot dot", build: func(b *RequestBuilder) *RequestBuilder { return b.Path("..", "admin") }},
+		{name: "empty", build: func(b *RequestBuilder) *RequestBuilder { return b.Path("issues", "") }},
+		{
+			name: "template that escapes the path",
+			build: func(b *RequestBuilder) *RequestBuilder {
+				return b.Template(uritemplate.MustParse("/issues/{id}"), uritemplate.Values{})
+			},
+		},
+	}
+	for _, tt := range tests {
+		u, err := tt.build(c.NewRequest()).URL()
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: URL() = %s, want error", tt.name, u)
+			} else if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if u.String() != tt.want {
+			t.Errorf("%s: URL() = %s, want %s", tt.name, u, tt.want)
+		}
+	}
+}
+
+func TestRequestBuilderHeaders(t *testing.T) {
+	c, err := NewClient("https://api.example.com/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := c.NewRequest().
+		Method(http.MethodPatch).
+		Header("X-Tag", "a").
+		Header("x-tag", "b").
+		Header("Accept", "text/plain").
+		Header("Content-Type", "application/merge-patch+json").
+		JSON(map[string]int{"estimate": 3}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{key: "X-Tag", want: []string{"a", "b"}},
+		{key: "Accept", want: []string{"text/plain"}},
+		{key: "Content-Type", want: []string{"application/merge-patch+json"}},
+	}
+	for _, tt := range tests {
+		if got := req.Header.Values(tt.key); !slices.Equal(got, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestRequestBuilderDo(t *testing.T) {
+	type call struct {
+		Method, Path, RawQuery, ContentType, Body string
+	}
+	c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(call{r.Method, r.URL.EscapedPath(), r.URL.RawQuery, r.Header.Get("Content-Type"), string(body)})
+	}))
+	tests := []struct {
+		name  string
+		build func(b *RequestBuilder) *RequestBuilder
+		want  call
+	}{
+		{
+			name:  "get",
+			build: func(b *RequestBuilder) *RequestBuilder { return b.Path("issues", "a b").Query("state", "open") },
+			want:  call{Method: "GET", Path: "/v1/learn-http/issues/a%20b", RawQuery: "state=open"},
+		},
+		{
+			name: "json",
+			build: func(b *RequestBuilder) *RequestBuilder {
+				return b.Method(http.MethodPatch).Path("issues", "1").JSON(map[string]int{"estimate": 3})
+			},
+			want: call{Method: "PATCH", Path: "/v1/learn-http/issues/1", ContentType: "application/json", Body: `{"estimate":3}`},
+		},
+		{
+			name: "form",
+			build: func(b *RequestBuilder) *RequestBuilder {
+				return b.Method(http.MethodPost).Path("login").Form(map[string][]string{"user": {"ada lovelace"}})
+			},
+			want: call{Method: "POST", Path: "/v1/learn-http/login", ContentType: "application/x-www-form-urlencoded", Body: "user=ada+lovelace"},
+		},
+	}
+	for _, tt := range tests {
+		var got call
+		if err := tt.build(c.NewRequest().Context(context.Background())).Do(&got); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: server got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package jello
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/internal/nameserver"
+)
+
+// IPPreference picks between IPv4 and IPv6 addresses of a host.
+type IPPreference int
+
+const (
+	// PreferNone tries addresses of the family the resolver listed first,
+	// falling back to the other family, like the standard dialer.
+	PreferNone IPPreference = iota
+	// PreferIPv4 tries IPv4 first and falls back to IPv6.
+	PreferIPv4
+	// PreferIPv6 tries IPv6 first and falls back to IPv4.
+	PreferIPv6
+	// IPv4Only never uses IPv6.
+	IPv4Only
+	// IPv6Only never uses IPv4.
+	IPv6Only
+)
+
+// HostResolver looks up the addresses of a host. *net.Resolver
+// implements it.
+type HostResolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// ttlResolver is a HostResolver that also knows how long its answer may
+// be kept. A negative ttl means it doesn't know. DoHResolver implements
+// it.
+type ttlResolver interface {
+	lookupNetIPTTL(ctx context.Context, network, host string) ([]netip.Addr, time.Duration, error)
+}
+
+// ResolverConfig changes how the client turns host names into addresses.
+type ResolverConfig struct {
+	// Overrides maps a host, or a host:port, to the addresses to use
+	// instead of looking it up, like curl --resolve. host:port entries
+	// win over host entries.
+	Overrides map[string][]string
+	// Nameserver, e.g. "10.0.0.2:53", sends lookups to that server
+	// instead of the ones the system is configured with.
+	Nameserver string
+	// Resolver replaces the lookup entirely. Nameserver is ignored when
+	// it is set.
+	Resolver HostResolver
+	// CacheTTL keeps lookups for this long. 0 disables the cache. When
+	// the resolver reports record TTLs, as DoHResolver does, a shorter
+	// record TTL wins.
+	CacheTTL time.Duration
+	// Prefer chooses which address family to try first.
+	Prefer IPPreference
+	// FallbackDelay is how long to wait for the first family before also
+	// trying the other one ("happy eyeballs"). Defaults to 300ms.
+	FallbackDelay time.Duration
+}
+
+// WithResolver resolves host names using cfg instead of the defaults.
+func WithResolver(cfg ResolverConfig) Option {
+	return func(c *Client) {
+		c.resolver = &cfg
+	}
+}
+
+// ParseResolve parses a curl --resolve value, "host:port:addr[,addr]",
+// into a key and addresses for ResolverConfig.Overrides. IPv6 addresses
+// may be written in brackets.
+func ParseResolve(v string) (hostPort string, addrs []string, err error) {
+	host, rest, ok := strings.Cut(v, ":")
+	if !ok {
+		return "", nil, fmt.Errorf("invalid resolve %q, want host:port:addr", v)
+	}
+	port, list, ok := strings.Cut(rest, ":")
+	if !ok || host == "" || port == "" || list == "" {
+		return "", nil, fmt.Errorf("invalid resolve %q, want host:port:addr", v)
+	}
+	for _, a := range strings.Split(list, ",") {
+		a = strings.TrimSuffix(strings.TrimPrefix(a, "["), "]")
+		if _, err := netip.ParseAddr(a); err != nil {
+			return "", nil, fmt.Errorf("invalid address in resolve %q: %w", v, err)
+		}
+		addrs = append(addrs, a)
+	}
+	return net.JoinHostPort(host, port), addrs, nil
+}
+
+// dialer returns the dial function for the client's transport.
+func (c *Client) dialer() dialFunc {
+	d := &net.Dialer{
+		Timeout:   c.transportConfig.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	if c.resolver == nil {
+		return d.DialContext
+	}
+	return newResolvingDialer(d, *c.resolver).DialContext
+}
+
+// resolvingDialer looks up hosts itself and races the address families.
+type resolvingDialer struct {
+	dialer   *net.Dialer
+	cfg      ResolverConfig
+	resolver HostResolver
+
+	mu    sync.Mutex
+	cache map[string]cachedAddrs
+}
+
+type cachedAddrs struct {
+	addrs   []netip.Addr
+	expires time.Time
+}
+
+func newResolvingDialer(d *net.Dialer, cfg ResolverConfig) *resolvingDialer {
+	if cfg.FallbackDelay <= 0 {
+		cfg.FallbackDelay = 300 * time.Millisecond
+	}
+	resolver := cfg.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+		if cfg.Nameserver != "" {
+			resolver = nameserver.Resolver(cfg.Nameserver)
+		}
+	}
+	return &resolvingDialer{
+		dialer:   d,
+		cfg:      cfg,
+		resolver: resolver,
+		cache:    map[string]cachedAddrs{},
+	}
+}
+
+func (d *resolvingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := d.lookup(ctx, host, port)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	primaries, fallbacks := d.order(addrs)
+	if len(primaries) == 0 {
+		// E.g. PreferIPv4 for a host that only has IPv6 addresses.
+		primaries, fallbacks = fallbacks, nil
+	}
+	if len(primaries) == 0 {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: fmt.Errorf("no suitable address for %s", host)}
+	}
+	return d.dialParallel(ctx, network, primaries, fallbacks, port)
+}
+
+func (d *resolvingDialer) lookup(ctx context.Context, host, port string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}, nil
+	}
+	for _, key := range []string{net.JoinHostPort(host, port), host} {
+		if list, ok := d.cfg.Overrides[key]; ok {
+			addrs := make([]netip.Addr, 0, len(list))
+			for _, a := range list {
+				addr, err := netip.ParseAddr(a)
+				if err != nil {
+					return nil, fmt.Errorf("invalid override for %s: %w", key, err)
+				}
+				addrs = append(addrs, addr)
+			}
+			return addrs, nil
+		}
+	}
+
+	if d.cfg.CacheTTL > 0 {
+		if addrs, ok := d.cached(host); ok {
+			return addrs, nil
+		}
+	}
+
+	// The standard dialer reports lookups to httptrace, so do the same.
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+	addrs, ttl, err := d.lookupNetIP(ctx, host)
+	if trace != nil && trace.DNSDone != nil {
+		info := httptrace.DNSDoneInfo{Err: err}
+		for _, a := range addrs {
+			info.Addrs = append(info.Addrs, net.IPAddr{IP: a.AsSlice(), Zone: a.Zone()})
+		}
+		trace.DNSDone(info)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl < 0 || ttl > d.cfg.CacheTTL {
+		ttl = d.cfg.CacheTTL
+	}
+	if ttl > 0 {
+		d.store(host, addrs, ttl)
+	}
+	return addrs, nil
+}
+
+// lookupNetIP asks the resolver, with the record TTL if it knows it.
+func (d *resolvingDialer) lookupNetIP(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+	if r, ok := d.resolver.(ttlResolver); ok {
+		return r.lookupNetIPTTL(ctx, "ip", host)
+	}
+	addrs, err := d.resolver.LookupNetIP(ctx, "ip", host)
+	return addrs, -1, err
+}
+
+// cached returns the cached addresses of host, dropping them if they
+// have expired.
+func (d *resolvingDialer) cached(host string) ([]netip.Addr, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry, ok := d.cache[host]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(entry.expires) {
+		delete(d.cache, host)
+		return nil, false
+	}
+	return entry.addrs, true
+}
+
+// store caches addrs for host, and sweeps out expired entries so hosts
+// that are never looked up again don't stay around.
+func (d *resolvingDialer) store(host string, addrs []netip.Addr, ttl time.Duration) {
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for h, entry := range d.cache {
+		if !now.Before(entry.expires) {
+			delete(d.cache, h)
+		}
+	}
+	d.cache[host] = cachedAddrs{addrs: addrs, expires: now.Add(ttl)}
+}
+
+// order splits addrs into the family to try first and the fallback.
+func (d *resolvingDialer) order(addrs []netip.Addr) (primaries, fallbacks []netip.Addr) {
+	var v4, v6 []netip.Addr
+	for _, a := range addrs {
+		if a.Unmap().Is4() {
+			v4 = append(v4, a.Unmap())
+		} else {
+			v6 = append(v6, a)
+		}
+	}
+	switch d.cfg.Prefer {
+	case PreferIPv4:
+		return v4, v6
+	case PreferIPv6:
+		return v6, v4
+	case IPv4Only:
+		return v4, nil
+	case IPv6Only:
+		return v6, nil
+	}
+	if len(addrs) > 0 && addrs[0].Unmap().Is4() {
+		return v4, v6
+	}
+	return v6, v4
+}
+
+// dialParallel tries primaries and, if they haven't connected after the
+// fallback delay or have failed, fallbacks too. The first connection
+// wins.
+func (d *resolvingDialer) dialParallel(ctx context.Context, network string, primaries, fallbacks []netip.Addr, port string) (net.Conn, error) {
+	if len(fallbacks) == 0 {
+		return d.dialSerial(ctx, network, primaries, port)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, 2)
+	start := func(addrs []netip.Addr) {
+		go func() {
+			conn, err := d.dialSerial(ctx, network, addrs, port)
+			results <- result{conn, err}
+		}()
+	}
+
+	start(primaries)
+	pending, fallbackStarted := 1, false
+	timer := time.NewTimer(d.cfg.FallbackDelay)
+	defer timer.Stop()
+
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				start(fallbacks)
+				pending, fallbackStarted = pending+1, true
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if pending > 0 {
+					// Close the loser if it connects anyway.
+					go func() {
+						if r := <-results; r.conn != nil {
+							r.conn.Close()
+						}
+					}()
+				}
+				return r.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if !fallbackStarted {
+				start(fallbacks)
+				pending, fallbackStarted = pending+1, true
+			}
+			if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+// dialSerial tries addrs one after another.
+func (d *resolvingDialer) dialSerial(ctx context.Context, network string, addrs []netip.Addr, port string) (net.Conn, error) {
+	var firstErr error
+	for _, a := range addrs {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(a.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if firstErr == nil {
+		firstErr = errors.New("no addresses to dial")
+	}
+	return nil, firstErr
+}
@@ -0,0 +1,180 @@
+package jello
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseResolve(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantKey  string
+		wantAddr []string
+		wantErr  bool
+	}{
+		{in: "example.com:443:127.0.0.1", wantKey: "example.com:443", wantAddr: []string{"127.0.0.1"}},
+		{in: "example.com:80:127.0.0.1,[::1]", wantKey: "example.com:80", wantAddr: []string{"127.0.0.1", "::1"}},
+		{in: "example.com:443", wantErr: true},
+		{in: "example.com:443:not-an-ip", wantErr: true},
+		{in: ":443:127.0.0.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		key, addrs, err := ParseResolve(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseResolve(%q) succeeded, want error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseResolve(%q): %v", tt.in, err)
+			continue
+		}
+		if key != tt.wantKey || !slices.Equal(addrs, tt.wantAddr) {
+			t.Errorf("ParseResolve(%q) = %s %q, want %s %q", tt.in, key, addrs, tt.wantKey, tt.wantAddr)
+		}
+	}
+}
+
+func TestResolverOrder(t *testing.T) {
+	v4 := netip.MustParseAddr("192.0.2.1")
+	v6 := netip.MustParseAddr("2001:db8::1")
+	tests := []struct {
+		prefer        IPPreference
+		addrs         []netip.Addr
+		wantPrimaries []netip.Addr
+		wantFallbacks []netip.Addr
+	}{
+		{prefer: PreferNone, addrs: []netip.Addr{v6, v4}, wantPrimaries: []netip.Addr{v6}, wantFallbacks: []netip.Addr{v4}},
+		{prefer: PreferNone, addrs: []netip.Addr{v4, v6}, wantPrimaries: []netip.Addr{v4}, wantFallbacks: []netip.Addr{v6}},
+		{prefer: PreferIPv4, addrs: []netip.Addr{v6, v4}, wantPrimaries: []netip.Addr{v4}, wantFallbacks: []netip.Addr{v6}},
+		{prefer: PreferIPv6, addrs: []netip.Addr{v4, v6}, wantPrimaries: []netip.Addr{v6}, wantFallbacks: []netip.Addr{v4}},
+		{prefer: IPv4Only, addrs: []netip.Addr{v6, v4}, wantPrimaries: []netip.Addr{v4}},
+		{prefer: IPv6Only, addrs: []netip.Addr{v4}},
+	}
+	for _, tt := range tests {
+		d := newResolvingDialer(&net.Dialer{}, ResolverConfig{Prefer: tt.prefer})
+		primaries, fallbacks := d.order(tt.addrs)
+		if !slices.Equal(primaries, tt.wantPrimaries) || !slices.Equal(fallbacks, tt.wantFallbacks) {
+			t.Errorf("order(%v) with preference %d = %v, %v, want %v, %v",
+				tt.addrs, tt.prefer, primaries, fallbacks, tt.wantPrimaries, tt.wantFallbacks)
+		}
+	}
+}
+
+// TestResolverDialsOtherFamily checks that preferring a family the host
+// doesn't have still connects, and that the Only settings don't.
+func TestResolverDialsOtherFamily(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	tests := []struct {
+		prefer  IPPreference
+		wantErr string
+	}{
+		{prefer: PreferNone},
+		{prefer: PreferIPv4},
+		{prefer: PreferIPv6},
+		{prefer: IPv4Only},
+		{prefer: IPv6Only, wantErr: "no suitable address"},
+	}
+	for _, tt := range tests {
+		c, err := NewClient("http://api.jello.test", WithResolver(ResolverConfig{
+			Overrides: map[string][]string{"api.jello.test": {"127.0.0.1"}},
+			Prefer:    tt.prefer,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := c.HTTPClient().Get("http://api.jello.test:" + u.Port() + "/")
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("preference %d: error = %v, want %q", tt.prefer, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("preference %d: %v", tt.prefer, err)
+			continue
+		}
+		res.Body.Close()
+	}
+}
+
+// countingResolver answers every lookup with 127.0.0.1 and the given
+// record TTL.
+type countingResolver struct {
+	ttl     time.Duration
+	lookups atomic.Int64
+}
+
+func (r *countingResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	addrs, _, err := r.lookupNetIPTTL(ctx, network, host)
+	return addrs, err
+}
+
+func (r *countingResolver) lookupNetIPTTL(ctx context.Context, network, host string) ([]netip.Addr, time.Duration, error) {
+	r.lookups.Add(1)
+	return []netip.Addr{netip.MustParseAddr("127.0.0.1")}, r.ttl, nil
+}
+
+func TestResolverCacheTTL(t *testing.T) {
+	tests := []struct {
+		name        string
+		cacheTTL    time.Duration
+		recordTTL   time.Duration
+		wantLookups int64
+	}{
+		{name: "cache off", cacheTTL: 0, recordTTL: time.Hour, wantLookups: 3},
+		{name: "record ttl unknown", cacheTTL: time.Hour, recordTTL: -1, wantLookups: 1},
+		{name: "record ttl longer", cacheTTL: time.Hour, recordTTL: 2 * time.Hour, wantLookups: 1},
+		{name: "record ttl zero", cacheTTL: time.Hour, recordTTL: 0, wantLookups: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &countingResolver{ttl: tt.recordTTL}
+			d := newResolvingDialer(&net.Dialer{}, ResolverConfig{Resolver: r, CacheTTL: tt.cacheTTL})
+			for range 3 {
+				if _, err := d.lookup(context.Background(), "api.jello.test", "443"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := r.lookups.Load(); got != tt.wantLookups {
+				t.Errorf("lookups = %d, want %d", got, tt.wantLookups)
+			}
+		})
+	}
+}
+
+func TestResolverCacheEvicts(t *testing.T) {
+	r := &countingResolver{ttl: -1}
+	d := newResolvingDialer(&net.Dialer{}, ResolverConfig{Resolver: r, CacheTTL: time.Hour})
+	d.store("old.jello.test", nil, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	if _, ok := d.cached("old.jello.test"); ok {
+		t.Error("expired entry was returned")
+	}
+	if _, ok := d.cache["old.jello.test"]; ok {
+		t.Error("expired entry was not deleted on lookup")
+	}
+
+	d.store("old.jello.test", nil, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, err := d.lookup(context.Background(), "api.jello.test", "443"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.cache["old.jello.test"]; ok || len(d.cache) != 1 {
+		t.Errorf("cache = %v, want only api.jello.test", d.cache)
+	}
+}
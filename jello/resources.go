@@ -0,0 +1,117 @@
+package jello
+
+import (
+	"context"
+	"crypto/rand"
+	"io"
+	"iter"
+	"net/http"
+)
+
+// Project is a Jello project.
+type Project struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Issue is a single issue on a project.
+type Issue struct {
+	Id       string `json:"id"`
+	Title    string `json:"title"`
+	Estimate int    `json:"estimate"`
+}
+
+// Attachment is a file attached to an issue.
+type Attachment struct {
+	Id       string `json:"id"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+}
+
+// Board groups issues for a team.
+type Board struct {
+	Id       int    `json:"id"`
+	Name     string `json:"name"`
+	TeamId   int    `json:"team"`
+	TeamName string `json:"team_name"`
+}
+
+// Comment is a comment left by a user.
+type Comment struct {
+	Id      string `json:"id"`
+	UserId  string `json:"user_id"`
+	Comment string `json:"comment"`
+}
+
+// ProjectsService handles the /projects endpoints.
+type ProjectsService struct {
+	client *Client
+}
+
+// List iterates over every project, fetching pages as needed.
+func (s *ProjectsService) List(ctx context.Context, opts *ListOptions) iter.Seq2[Project, error] {
+	return list[Project](ctx, s.client, opts, "projects")
+}
+
+// IssuesService handles the /issues endpoints.
+type IssuesService struct {
+	client *Client
+}
+
+// List iterates over every issue, fetching pages as needed.
+func (s *IssuesService) List(ctx context.Context, opts *ListOptions) iter.Seq2[Issue, error] {
+	return list[Issue](ctx, s.client, opts, "issues")
+}
+
+// UploadAttachment attaches the file read from r to the issue with the
+// given id. The file is streamed rather than read into memory. If r is
+// an io.Seeker a failed upload can be retried; each upload gets its own
+// Idempotency-Key so the server can tell a retry from a second upload.
+func (s *IssuesService) UploadAttachment(ctx context.Context, issueID, filename string, r io.Reader) (Attachment, error) {
+	u, err := s.client.endpoint("issues", issueID, "attachments")
+	if err != nil {
+		return Attachment{}, err
+	}
+	body := NewMultipart().File("file", filename, r).Body()
+	req, err := s.client.newRequest(ctx, http.MethodPost, u, body)
+	if err != nil {
+		return Attachment{}, err
+	}
+	req.Header.Set("Idempotency-Key", rand.Text())
+	var attachment Attachment
+	if err := s.client.doRequest(req, &attachment); err != nil {
+		return Attachment{}, err
+	}
+	return attachment, nil
+}
+
+// BoardsService handles the /boards endpoints.
+type BoardsService struct {
+	client *Client
+}
+
+// List iterates over every board, fetching pages as needed.
+func (s *BoardsService) List(ctx context.Context, opts *ListOptions) iter.Seq2[Board, error] {
+	return list[Board](ctx, s.client, opts, "boards")
+}
+
+// CommentsService handles the /comments endpoints.
+type CommentsService struct {
+	client *Client
+}
+
+// List iterates over every comment, fetching pages as needed.
+func (s *CommentsService) List(ctx context.Context, opts *ListOptions) iter.Seq2[Comment, error] {
+	return list[Comment](ctx, s.client, opts, "comments")
+}
+
+func list[T any](ctx context.Context, c *Client, opts *ListOptions, segments ...string) iter.Seq2[T, error] {
+	u, err := c.endpoint(segments...)
+	if err != nil {
+		return func(yield func(T, error) bool) {
+			var zero T
+			yield(zero, err)
+		}
+	}
+	return paginate[T](ctx, c, u, opts)
+}
@@ -0,0 +1,67 @@
+package jello
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// DefaultTimeout is how long a request may take, including reading the
+// body, unless the client or the call sets something else. It matches
+// the 10 second http.Client timeout used in the methods chapter.
+const DefaultTimeout = 10 * time.Second
+
+// WithTimeout sets the default timeout for every request made by the
+// client. 0 disables it, leaving only the context deadline.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+type timeoutKey struct{}
+
+// WithRequestTimeout returns a context that overrides the client's
+// timeout for calls made with it. Unlike http.Client.Timeout this can be
+// longer or shorter than the default without building a new client.
+// A deadline already set on ctx still applies if it is earlier.
+func WithRequestTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
+
+// withTimeout applies the per-call or client timeout to ctx.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	d := c.timeout
+	if override, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		d = override
+	}
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// cancelBody releases the request context once the body is closed, and
+// reports reads cut short by that context as context errors.
+type cancelBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		if ctxErr := b.ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+			err = errors.Join(ctxErr, err)
+		}
+	}
+	return n, err
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,138 @@
+package jello
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// slowHandler waits for delay before writing the headers, then for
+// bodyDelay before writing the body. It gives up when the client goes.
+func slowHandler(delay, bodyDelay time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("["))
+		w.(http.Flusher).Flush()
+		select {
+		case <-time.After(bodyDelay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Write([]byte("]"))
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	tests := []struct {
+		name      string
+		delay     time.Duration
+		bodyDelay time.Duration
+		client    time.Duration
+		ctx       func(context.Context) (context.Context, context.CancelFunc)
+		wantErr   error
+	}{
+		{name: "fast enough", client: time.Second},
+		{name: "client timeout", delay: time.Second, client: 50 * time.Millisecond, wantErr: ErrTimeout},
+		{name: "client timeout while reading the body", bodyDelay: time.Second, client: 50 * time.Millisecond, wantErr: ErrTimeout},
+		{name: "no client timeout", delay: 20 * time.Millisecond},
+		{
+			name:   "per-call timeout longer than the client's",
+			delay:  100 * time.Millisecond,
+			client: 20 * time.Millisecond,
+			ctx: func(ctx context.Context) (context.Context, context.CancelFunc) {
+				return WithRequestTimeout(ctx, time.Second), func() {}
+			},
+		},
+		{
+			name:   "per-call timeout shorter than the client's",
+			delay:  time.Second,
+			client: time.Minute,
+			ctx: func(ctx context.Context) (context.Context, context.CancelFunc) {
+				return WithRequestTimeout(ctx, 20*time.Millisecond), func() {}
+			},
+			wantErr: ErrTimeout,
+		},
+		{
+			name:   "context deadline earlier than the client timeout",
+			delay:  time.Second,
+			client: time.Minute,
+			ctx: func(ctx context.Context) (context.Context, context.CancelFunc) {
+				return context.WithTimeout(ctx, 20*time.Millisecond)
+			},
+			wantErr: ErrTimeout,
+		},
+		{
+			name:   "canceled by the caller",
+			delay:  time.Second,
+			client: time.Minute,
+			ctx: func(ctx context.Context) (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(ctx)
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			wantErr: ErrCanceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t, slowHandler(tt.delay, tt.bodyDelay), WithTimeout(tt.client))
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx(ctx)
+			}
+			defer cancel()
+
+			_, err := c.Locations.List(ctx)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			var reqErr *RequestError
+			if !errors.As(err, &reqErr) {
+				t.Fatalf("error = %T, want *RequestError", err)
+			}
+			if tt.wantErr == ErrTimeout && (!reqErr.Timeout() || reqErr.Canceled()) {
+				t.Errorf("Timeout() = %v, Canceled() = %v", reqErr.Timeout(), reqErr.Canceled())
+			}
+			if tt.wantErr == ErrCanceled && (reqErr.Timeout() || !reqErr.Canceled()) {
+				t.Errorf("Timeout() = %v, Canceled() = %v", reqErr.Timeout(), reqErr.Canceled())
+			}
+		})
+	}
+}
+
+func TestListStopsWhenCanceled(t *testing.T) {
+	c, _ := newTestClient(t, pagedProjects(10, "link"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := 0
+	var err error
+	for _, e := range c.Projects.List(ctx, nil) {
+		if e != nil {
+			err = e
+			break
+		}
+		if n++; n == 2 {
+			cancel()
+		}
+	}
+	if !errors.Is(err, ErrCanceled) {
+		t.Fatalf("error = %v after %d items, want ErrCanceled", err, n)
+	}
+	if n != 2 {
+		t.Errorf("got %d items, want 2", n)
+	}
+}
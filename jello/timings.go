@@ -0,0 +1,232 @@
+package jello
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings breaks a request down into the phases curl reports with -w:
+// DNS lookup, TCP connect, TLS handshake, waiting for the first byte and
+// transferring the body. Phases that didn't happen, e.g. DNS and
+// connect on a reused connection, are 0.
+type Timings struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	done         time.Time
+	reused       bool
+	remoteAddr   string
+}
+
+type timingsKey struct{}
+
+// TimeRequest returns a copy of req that records its timings into the
+// returned Timings. Call Done once the body has been read, or use
+// CaptureTimings, which does it when the body is closed.
+func TimeRequest(req *http.Request) (*http.Request, *Timings) {
+	t := &Timings{start: time.Now()}
+	ctx := context.WithValue(req.Context(), timingsKey{}, t)
+	ctx = httptrace.WithClientTrace(ctx, t.trace())
+	return req.WithContext(ctx), t
+}
+
+// CaptureTimings records Timings for every attempt. Get them with
+// ResponseTimings.
+func CaptureTimings() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req, t := TimeRequest(req)
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				t.Done()
+				return nil, err
+			}
+			res.Body = &endBody{ReadCloser: &eofBody{ReadCloser: res.Body, eof: t.Done}, end: t.Done}
+			return res, nil
+		})
+	}
+}
+
+// ResponseTimings returns the Timings recorded for res, if its request
+// was made with TimeRequest or through CaptureTimings.
+func ResponseTimings(res *http.Response) (*Timings, bool) {
+	if res == nil || res.Request == nil {
+		return nil, false
+	}
+	t, ok := res.Request.Context().Value(timingsKey{}).(*Timings)
+	return t, ok
+}
+
+// Done marks the end of the transfer. Only the first call counts.
+func (t *Timings) Done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done.IsZero() {
+		t.done = time.Now()
+	}
+}
+
+func (t *Timings) trace() *httptrace.ClientTrace {
+	set := func(field *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if field.IsZero() {
+			*field = time.Now()
+		}
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:     func(httptrace.DNSStartInfo) { set(&t.dnsStart) },
+		DNSDone:      func(httptrace.DNSDoneInfo) { set(&t.dnsDone) },
+		ConnectStart: func(string, string) { set(&t.connectStart) },
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				set(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() { set(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { set(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			set(&t.gotConn)
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&t.wroteRequest) },
+		GotFirstResponseByte: func() { set(&t.firstByte) },
+	}
+}
+
+// between returns b-a, or 0 if either is unset.
+func between(a, b time.Time) time.Duration {
+	if a.IsZero() || b.IsZero() {
+		return 0
+	}
+	return b.Sub(a)
+}
+
+// DNSLookup is how long resolving the host name took.
+func (t *Timings) DNSLookup() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return between(t.dnsStart, t.dnsDone)
+}
+
+// TCPConnect is how long the TCP connection took to establish.
+func (t *Timings) TCPConnect() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return between(t.connectStart, t.connectDone)
+}
+
+// TLSHandshake is how long the TLS handshake took.
+func (t *Timings) TLSHandshake() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return between(t.tlsStart, t.tlsDone)
+}
+
+// ServerProcessing is the time between writing the request and the
+// first byte of the response.
+func (t *Timings) ServerProcessing() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return between(t.wroteRequest, t.firstByte)
+}
+
+// TimeToFirstByte is the time from the start to the first byte of the
+// response, like curl's time_starttransfer.
+func (t *Timings) TimeToFirstByte() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return between(t.start, t.firstByte)
+}
+
+// ContentTransfer is how long reading the body took after the first byte.
+func (t *Timings) ContentTransfer() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return between(t.firstByte, t.done)
+}
+
+// Total is the time from the start until Done.
+func (t *Timings) Total() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return between(t.start, t.done)
+}
+
+// The following are cumulative from the start, matching curl's
+// time_namelookup, time_connect, time_appconnect and time_pretransfer.
+
+// NameLookupDone is the time from the start until DNS finished.
+func (t *Timings) NameLookupDone() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return between(t.start, t.dnsDone)
+}
+
+// ConnectDone is the time from the start until TCP connected.
+func (t *Timings) ConnectDone() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return between(t.start, t.connectDone)
+}
+
+// AppConnectDone is the time from the start until TLS finished.
+func (t *Timings) AppConnectDone() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return between(t.start, t.tlsDone)
+}
+
+// PreTransferDone is the time from the start until the connection was
+// ready to send the request.
+func (t *Timings) PreTransferDone() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return between(t.start, t.gotConn)
+}
+
+// Reused reports whether the connection came from the pool.
+func (t *Timings) Reused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.reused
+}
+
+// RemoteAddr is the address of the server the request went to.
+func (t *Timings) RemoteAddr() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.remoteAddr
+}
+
+// eofBody calls eof when the body has been read to the end.
+type eofBody struct {
+	io.ReadCloser
+	eof func()
+}
+
+func (b *eofBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.eof()
+	}
+	return n, err
+}
@@ -0,0 +1,101 @@
+package jello
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimings(t *testing.T) {
+	tests := []struct {
+		name    string
+		tls     bool
+		wantTLS bool
+	}{
+		{name: "http"},
+		{name: "https", tls: true, wantTLS: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(20 * time.Millisecond)
+				w.Write([]byte("["))
+				w.(http.Flusher).Flush()
+				time.Sleep(20 * time.Millisecond)
+				w.Write([]byte("]"))
+			})
+			var srv *httptest.Server
+			if tt.tls {
+				srv = httptest.NewTLSServer(h)
+			} else {
+				srv = httptest.NewServer(h)
+			}
+			defer srv.Close()
+			hc := srv.Client()
+			hc.Transport = CaptureTimings()(hc.Transport)
+
+			for i, wantReused := range []bool{false, true} {
+				res, err := hc.Get(srv.URL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				io.ReadAll(res.Body)
+				res.Body.Close()
+
+				tm, ok := ResponseTimings(res)
+				if !ok {
+					t.Fatal("no timings on the response")
+				}
+				if tm.Reused() != wantReused {
+					t.Errorf("request %d: Reused = %v, want %v", i, tm.Reused(), wantReused)
+				}
+				if wantReused {
+					if tm.TCPConnect() != 0 || tm.TLSHandshake() != 0 {
+						t.Errorf("reused connection has connect %s, tls %s", tm.TCPConnect(), tm.TLSHandshake())
+					}
+				} else {
+					if tm.TCPConnect() <= 0 {
+						t.Errorf("TCPConnect = %s", tm.TCPConnect())
+					}
+					if (tm.TLSHandshake() > 0) != tt.wantTLS || (tm.AppConnectDone() > 0) != tt.wantTLS {
+						t.Errorf("TLSHandshake = %s, AppConnectDone = %s", tm.TLSHandshake(), tm.AppConnectDone())
+					}
+				}
+				// The server is on an IP address, so there is no lookup.
+				if tm.DNSLookup() != 0 {
+					t.Errorf("DNSLookup = %s for an IP address", tm.DNSLookup())
+				}
+				if tm.ServerProcessing() < 20*time.Millisecond {
+					t.Errorf("ServerProcessing = %s, want at least the handler's 20ms", tm.ServerProcessing())
+				}
+				if tm.ContentTransfer() < 20*time.Millisecond {
+					t.Errorf("ContentTransfer = %s, want at least 20ms", tm.ContentTransfer())
+				}
+				if tm.Total() < tm.TimeToFirstByte()+tm.ContentTransfer()-time.Millisecond {
+					t.Errorf("Total %s < first byte %s + transfer %s", tm.Total(), tm.TimeToFirstByte(), tm.ContentTransfer())
+				}
+				if tm.PreTransferDone() > tm.TimeToFirstByte() {
+					t.Errorf("PreTransferDone %s after TimeToFirstByte %s", tm.PreTransferDone(), tm.TimeToFirstByte())
+				}
+				if tm.RemoteAddr() != srv.Listener.Addr().String() {
+					t.Errorf("RemoteAddr = %s, want %s", tm.RemoteAddr(), srv.Listener.Addr())
+				}
+			}
+		})
+	}
+}
+
+func TestTimingsFailedRequest(t *testing.T) {
+	req, _ := http.NewRequestWithContext(context.Background(), "GET", "http://127.0.0.1:1", nil)
+	req, tm := TimeRequest(req)
+	if _, err := http.DefaultTransport.RoundTrip(req); err == nil {
+		t.Fatal("request to a closed port succeeded")
+	}
+	tm.Done()
+	if tm.Total() <= 0 || tm.TimeToFirstByte() != 0 {
+		t.Errorf("Total = %s, TimeToFirstByte = %s", tm.Total(), tm.TimeToFirstByte())
+	}
+}
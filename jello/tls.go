@@ -0,0 +1,176 @@
+package jello
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrUnknownAuthority matches a CertificateError for a certificate
+	// signed by a CA that isn't trusted.
+	ErrUnknownAuthority = errors.New("jello: certificate signed by unknown authority")
+
+	// ErrHostnameMismatch matches a CertificateError for a certificate
+	// that isn't valid for the host that was dialed.
+	ErrHostnameMismatch = errors.New("jello: certificate is not valid for host")
+
+	// ErrCertificateExpired matches a CertificateError for a certificate
+	// that has expired or is not valid yet.
+	ErrCertificateExpired = errors.New("jello: certificate has expired")
+
+	// ErrPinMismatch matches a CertificateError for a chain with none of
+	// the pinned public keys.
+	ErrPinMismatch = errors.New("jello: certificate does not match pinned keys")
+)
+
+// TLSConfig customizes how the client verifies servers and identifies
+// itself to them.
+type TLSConfig struct {
+	// RootCAs is PEM data with CA certificates to trust in addition to
+	// the system roots, e.g. a company CA.
+	RootCAs []byte
+	// Certificates are presented to servers that ask for a client
+	// certificate (mutual TLS). Load them with tls.LoadX509KeyPair.
+	Certificates []tls.Certificate
+	// Pins maps a host name to the public keys it may use, as SPKIHash
+	// values. A connection to a pinned host fails unless some certificate
+	// in the verified chain has one of them. Pin a backup key too, or
+	// rotating the certificate will lock the client out. Hosts must be
+	// names, not IP addresses, since pins are matched on the TLS server
+	// name.
+	Pins map[string][]string
+	// MinVersion is the lowest TLS version accepted, e.g.
+	// tls.VersionTLS13. Defaults to TLS 1.2.
+	MinVersion uint16
+}
+
+// WithTLSConfig sets how TLS connections are verified. Like
+// WithTransportConfig, it has no effect with WithHTTPClient.
+func WithTLSConfig(cfg TLSConfig) Option {
+	return func(c *Client) {
+		c.tlsConfig = &cfg
+	}
+}
+
+// SPKIHash returns the pin for cert: the base64 SHA-256 of its subject
+// public key info, the same value as HPKP's pin-sha256 and
+//
+//	openssl x509 -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// build turns cfg into a tls.Config.
+func (cfg *TLSConfig) build() (*tls.Config, error) {
+	tc := &tls.Config{
+		MinVersion:   cfg.MinVersion,
+		Certificates: cfg.Certificates,
+	}
+	if tc.MinVersion == 0 {
+		tc.MinVersion = tls.VersionTLS12
+	}
+
+	if len(cfg.RootCAs) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(cfg.RootCAs) {
+			return nil, errors.New("error loading root CAs: no certificates found in PEM data")
+		}
+		tc.RootCAs = pool
+	}
+
+	if len(cfg.Pins) > 0 {
+		pins := make(map[string][]string, len(cfg.Pins))
+		for host, hashes := range cfg.Pins {
+			if net.ParseIP(host) != nil {
+				return nil, fmt.Errorf("invalid pin host %q: pins need a host name, not an IP address", host)
+			}
+			for _, h := range hashes {
+				h = strings.TrimPrefix(h, "sha256/")
+				if b, err := base64.StdEncoding.DecodeString(h); err != nil || len(b) != sha256.Size {
+					return nil, fmt.Errorf("invalid pin %q for %s: want base64 SHA-256", h, host)
+				}
+				pins[strings.ToLower(host)] = append(pins[strings.ToLower(host)], h)
+			}
+		}
+		tc.VerifyConnection = func(cs tls.ConnectionState) error {
+			return checkPins(cs, pins[strings.ToLower(cs.ServerName)])
+		}
+	}
+	return tc, nil
+}
+
+// checkPins runs after the chain has been verified, so only keys in a
+// chain that leads to a trusted root count.
+func checkPins(cs tls.ConnectionState, pins []string) error {
+	if len(pins) == 0 {
+		return nil
+	}
+	for _, chain := range cs.VerifiedChains {
+		for _, cert := range chain {
+			if slices.Contains(pins, SPKIHash(cert)) {
+				return nil
+			}
+		}
+	}
+	return &CertificateError{Host: cs.ServerName, Reason: ErrPinMismatch}
+}
+
+// CertificateError is a TLS connection that failed because the server's
+// certificate was rejected. Reason is one of ErrUnknownAuthority,
+// ErrHostnameMismatch, ErrCertificateExpired or ErrPinMismatch, so
+// errors.Is works with any of them.
+type CertificateError struct {
+	Host   string
+	Reason error
+	// Err is the error from crypto/x509, if there was one.
+	Err error
+}
+
+func (e *CertificateError) Error() string {
+	// The x509 message already says what went wrong, in more detail.
+	if e.Err == nil {
+		return fmt.Sprintf("tls: %s: %v", e.Host, e.Reason)
+	}
+	return fmt.Sprintf("tls: %s: %v", e.Host, e.Err)
+}
+
+func (e *CertificateError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Reason}
+	}
+	return []error{e.Reason, e.Err}
+}
+
+// certificateError replaces the verification errors crypto/tls returns
+// with a CertificateError, and leaves other errors alone.
+func certificateError(host string, err error) error {
+	var certErr *CertificateError
+	if errors.As(err, &certErr) {
+		return certErr
+	}
+	var (
+		unknown  x509.UnknownAuthorityError
+		hostname x509.HostnameError
+		invalid  x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &unknown):
+		return &CertificateError{Host: host, Reason: ErrUnknownAuthority, Err: unknown}
+	case errors.As(err, &hostname):
+		return &CertificateError{Host: host, Reason: ErrHostnameMismatch, Err: hostname}
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		return &CertificateError{Host: host, Reason: ErrCertificateExpired, Err: invalid}
+	}
+	return err
+}
@@ -0,0 +1,182 @@
+package jello_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+	"github.com/JavierLU90/http_clients_go/jello/jellotest"
+)
+
+// newCertServer starts a TLS server presenting cert and returns the
+// base URL of a client that reaches it as api.jello.test.
+func newCertServer(t *testing.T, cert tls.Certificate, clientCAs *x509.CertPool) (string, jello.Option) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "[]")
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	// Rejected handshakes are the point of most tests, so don't log them.
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	if clientCAs != nil {
+		srv.TLS.ClientCAs = clientCAs
+		srv.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	return "https://api.jello.test:" + u.Port(), jello.WithResolver(jello.ResolverConfig{
+		Overrides: map[string][]string{"api.jello.test": {"127.0.0.1"}},
+	})
+}
+
+func TestTLSVerification(t *testing.T) {
+	ca := jellotest.NewCertificate(t, jellotest.CertOptions{CommonName: "Jello Test CA", IsCA: true})
+	otherCA := jellotest.NewCertificate(t, jellotest.CertOptions{CommonName: "Other CA", IsCA: true})
+	leaf := func(opts jellotest.CertOptions) tls.Certificate {
+		if opts.Hosts == nil {
+			opts.Hosts = []string{"api.jello.test"}
+		}
+		opts.Parent = &ca
+		return jellotest.NewCertificate(t, opts)
+	}
+	good := leaf(jellotest.CertOptions{CommonName: "api.jello.test"})
+	roots := jellotest.CertPEM(ca)
+
+	tests := []struct {
+		name    string
+		cert    tls.Certificate
+		cfg     jello.TLSConfig
+		wantErr error
+	}{
+		{name: "trusted", cert: good, cfg: jello.TLSConfig{RootCAs: roots}},
+		{
+			name: "leaf pin",
+			cert: good,
+			cfg:  jello.TLSConfig{RootCAs: roots, Pins: map[string][]string{"api.jello.test": {jello.SPKIHash(good.Leaf)}}},
+		},
+		{
+			name: "ca pin with prefix",
+			cert: good,
+			cfg:  jello.TLSConfig{RootCAs: roots, Pins: map[string][]string{"API.jello.test": {"sha256/" + jello.SPKIHash(ca.Leaf)}}},
+		},
+		{
+			name: "pin for another host",
+			cert: good,
+			cfg:  jello.TLSConfig{RootCAs: roots, Pins: map[string][]string{"other.jello.test": {jello.SPKIHash(otherCA.Leaf)}}},
+		},
+		{
+			name:    "bad pin",
+			cert:    good,
+			cfg:     jello.TLSConfig{RootCAs: roots, Pins: map[string][]string{"api.jello.test": {jello.SPKIHash(otherCA.Leaf)}}},
+			wantErr: jello.ErrPinMismatch,
+		},
+		{
+			name:    "unknown authority",
+			cert:    good,
+			cfg:     jello.TLSConfig{RootCAs: jellotest.CertPEM(otherCA)},
+			wantErr: jello.ErrUnknownAuthority,
+		},
+		{
+			name:    "self-signed",
+			cert:    jellotest.NewCertificate(t, jellotest.CertOptions{Hosts: []string{"api.jello.test"}}),
+			cfg:     jello.TLSConfig{RootCAs: roots},
+			wantErr: jello.ErrUnknownAuthority,
+		},
+		{
+			name: "expired",
+			cert: leaf(jellotest.CertOptions{
+				NotBefore: time.Now().Add(-48 * time.Hour),
+				NotAfter:  time.Now().Add(-24 * time.Hour),
+			}),
+			cfg:     jello.TLSConfig{RootCAs: roots},
+			wantErr: jello.ErrCertificateExpired,
+		},
+		{
+			name:    "hostname mismatch",
+			cert:    leaf(jellotest.CertOptions{Hosts: []string{"other.jello.test"}}),
+			cfg:     jello.TLSConfig{RootCAs: roots},
+			wantErr: jello.ErrHostnameMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, resolve := newCertServer(t, tt.cert, nil)
+			c, err := jello.NewClient(base, resolve, jello.WithTLSConfig(tt.cfg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.Locations.List(context.Background())
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			var certErr *jello.CertificateError
+			if !errors.As(err, &certErr) || certErr.Host != "api.jello.test" {
+				t.Errorf("error = %#v, want a CertificateError for api.jello.test", err)
+			}
+		})
+	}
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	ca := jellotest.NewCertificate(t, jellotest.CertOptions{CommonName: "Jello Test CA", IsCA: true})
+	server := jellotest.NewCertificate(t, jellotest.CertOptions{Hosts: []string{"api.jello.test"}, Parent: &ca})
+	client := jellotest.NewCertificate(t, jellotest.CertOptions{CommonName: "jello-cli", Hosts: []string{}, Parent: &ca})
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.Leaf)
+
+	tests := []struct {
+		name    string
+		certs   []tls.Certificate
+		wantErr bool
+	}{
+		{name: "with certificate", certs: []tls.Certificate{client}},
+		{name: "without certificate", wantErr: true},
+	}
+	for _, tt := range tests {
+		base, resolve := newCertServer(t, server, clientCAs)
+		c, err := jello.NewClient(base, resolve, jello.WithTLSConfig(jello.TLSConfig{
+			RootCAs:      jellotest.CertPEM(ca),
+			Certificates: tt.certs,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.Locations.List(context.Background())
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  jello.TLSConfig
+	}{
+		{name: "no PEM", cfg: jello.TLSConfig{RootCAs: []byte("not a certificate")}},
+		{name: "pin for IP", cfg: jello.TLSConfig{Pins: map[string][]string{"127.0.0.1": {jello.SPKIHash(&x509.Certificate{})}}}},
+		{name: "pin not base64", cfg: jello.TLSConfig{Pins: map[string][]string{"api.jello.test": {"not base64!"}}}},
+		{name: "pin wrong size", cfg: jello.TLSConfig{Pins: map[string][]string{"api.jello.test": {"c2hvcnQ="}}}},
+	}
+	for _, tt := range tests {
+		if _, err := jello.NewClient("https://api.jello.test", jello.WithTLSConfig(tt.cfg)); err == nil {
+			t.Errorf("%s: NewClient succeeded, want error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package jello
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Attribute is a key/value pair on a span or metric. Keys follow the
+// OpenTelemetry HTTP semantic conventions, e.g. "http.request.method".
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// SpanContext identifies a span for the W3C traceparent header.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// IsValid reports whether both ids are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Traceparent formats sc as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// Tracer starts spans. It is shaped like OpenTelemetry's trace.Tracer so
+// an adapter only has to convert attributes and span contexts.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	AddEvent(name string, attrs ...Attribute)
+	RecordError(err error)
+	SpanContext() SpanContext
+	End()
+}
+
+// Metrics receives counters and histogram observations. Like Tracer, it
+// maps directly onto OpenTelemetry's Int64Counter and Float64Histogram.
+type Metrics interface {
+	Add(ctx context.Context, name string, n int64, attrs ...Attribute)
+	Record(ctx context.Context, name string, value float64, attrs ...Attribute)
+}
+
+// Metric names recorded by RecordMetrics.
+const (
+	// MetricRequestCount counts attempts.
+	MetricRequestCount = "http.client.request.count"
+	// MetricRequestDuration is a histogram of attempt durations in
+	// seconds, up to the response headers.
+	MetricRequestDuration = "http.client.request.duration"
+)
+
+// Tracing starts a client span for every attempt, injects a W3C
+// traceparent header and records DNS, connect, TLS and first byte
+// events from httptrace. The span ends when the body is closed.
+func Tracing(tracer Tracer) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx, span := tracer.Start(req.Context(), req.Method)
+			span.SetAttributes(requestAttributes(req)...)
+			if attempt := Attempt(ctx); attempt > 1 {
+				span.SetAttributes(Attribute{"http.request.resend_count", attempt - 1})
+			}
+
+			ctx = httptrace.WithClientTrace(ctx, spanTrace(span))
+			req = req.Clone(ctx)
+			if sc := span.SpanContext(); sc.IsValid() {
+				req.Header.Set("Traceparent", sc.Traceparent())
+			}
+
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				span.SetAttributes(Attribute{"error.type", errorType(err)})
+				span.RecordError(err)
+				span.End()
+				return nil, err
+			}
+			span.SetAttributes(
+				Attribute{"http.response.status_code", res.StatusCode},
+				Attribute{"network.protocol.version", protocolVersion(res)},
+			)
+			if res.StatusCode >= 400 {
+				span.SetAttributes(Attribute{"error.type", strconv.Itoa(res.StatusCode)})
+			}
+			res.Body = &endBody{ReadCloser: res.Body, end: span.End}
+			return res, nil
+		})
+	}
+}
+
+// RecordMetrics counts attempts and records their duration to m.
+func RecordMetrics(m Metrics) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			res, err := next.RoundTrip(req)
+			elapsed := time.Since(start).Seconds()
+
+			attrs := []Attribute{
+				{"http.request.method", req.Method},
+				{"server.address", req.URL.Hostname()},
+			}
+			if err != nil {
+				attrs = append(attrs, Attribute{"error.type", errorType(err)})
+			} else {
+				attrs = append(attrs, Attribute{"http.response.status_code", res.StatusCode})
+				if res.StatusCode >= 400 {
+					attrs = append(attrs, Attribute{"error.type", strconv.Itoa(res.StatusCode)})
+				}
+			}
+			ctx := req.Context()
+			m.Add(ctx, MetricRequestCount, 1, attrs...)
+			m.Record(ctx, MetricRequestDuration, elapsed, attrs...)
+			return res, err
+		})
+	}
+}
+
+func requestAttributes(req *http.Request) []Attribute {
+	attrs := []Attribute{
+		{"http.request.method", req.Method},
+		{"url.full", req.URL.Redacted()},
+		{"url.scheme", req.URL.Scheme},
+		{"server.address", req.URL.Hostname()},
+	}
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, Attribute{"server.port", p})
+	}
+	return attrs
+}
+
+func protocolVersion(res *http.Response) string {
+	if res.ProtoMinor == 0 {
+		return strconv.Itoa(res.ProtoMajor)
+	}
+	return strconv.Itoa(res.ProtoMajor) + "." + strconv.Itoa(res.ProtoMinor)
+}
+
+// errorType is a low-cardinality name for err, for the error.type
+// attribute.
+func errorType(err error) string {
+	switch {
+	case isTimeout(err):
+		return "timeout"
+	case isCanceled(err):
+		return "canceled"
+	}
+	return "_OTHER"
+}
+
+// spanTrace turns connection events into span events.
+func spanTrace(span Span) *httptrace.ClientTrace {
+	// Connection attempts for happy eyeballs can run in parallel.
+	var mu sync.Mutex
+	event := func(name string, attrs ...Attribute) {
+		mu.Lock()
+		defer mu.Unlock()
+		span.AddEvent(name, attrs...)
+	}
+	return &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			event("http.get_conn", Attribute{"server.address", hostPort})
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			event("http.got_conn",
+				Attribute{"http.conn.reused", info.Reused},
+				Attribute{"http.conn.was_idle", info.WasIdle})
+		},
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			event("dns.start", Attribute{"server.address", info.Host})
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			attrs := []Attribute{{"dns.addresses", len(info.Addrs)}}
+			if info.Err != nil {
+				attrs = append(attrs, Attribute{"error.message", info.Err.Error()})
+			}
+			event("dns.done", attrs...)
+		},
+		ConnectStart: func(network, addr string) {
+			event("connect.start", Attribute{"network.peer.address", addr})
+		},
+		ConnectDone: func(network, addr string, err error) {
+			attrs := []Attribute{{"network.peer.address", addr}}
+			if err != nil {
+				attrs = append(attrs, Attribute{"error.message", err.Error()})
+			}
+			event("connect.done", attrs...)
+		},
+		TLSHandshakeStart: func() {
+			event("tls.start")
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			attrs := []Attribute{{"tls.protocol.version", tls.VersionName(state.Version)}}
+			if err != nil {
+				attrs = append(attrs, Attribute{"error.message", err.Error()})
+			}
+			event("tls.done", attrs...)
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			event("http.wrote_request")
+		},
+		GotFirstResponseByte: func() {
+			event("http.first_byte")
+		},
+	}
+}
+
+// endBody calls end once when the body is closed.
+type endBody struct {
+	io.ReadCloser
+	once sync.Once
+	end  func()
+}
+
+func (b *endBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.end)
+	return err
+}
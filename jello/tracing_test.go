@@ -0,0 +1,158 @@
+package jello_test
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+	"github.com/JavierLU90/http_clients_go/jello/jellotest"
+)
+
+func TestTracing(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		wantSpans  int
+		wantStatus int
+		wantError  string
+	}{
+		{name: "ok", statuses: []int{200}, wantSpans: 1, wantStatus: 200},
+		{name: "server error", statuses: []int{500}, wantSpans: 1, wantStatus: 500, wantError: "500"},
+		{name: "retried", statuses: []int{503, 200}, wantSpans: 2, wantStatus: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				calls        atomic.Int32
+				traceparents []string
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				traceparents = append(traceparents, r.Header.Get("Traceparent"))
+				w.WriteHeader(tt.statuses[calls.Add(1)-1])
+				w.Write([]byte("[]"))
+			}))
+			defer srv.Close()
+
+			tracer := &jellotest.Tracer{}
+			c, err := jello.NewClient(srv.URL,
+				jello.WithMiddleware(jello.StageRetry, jello.Retry(jello.RetryPolicy{BaseDelay: time.Millisecond})),
+				jello.WithMiddleware(jello.StageObserve, jello.Tracing(tracer)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Locations.List(context.Background())
+
+			spans := tracer.Spans()
+			if len(spans) != tt.wantSpans {
+				t.Fatalf("got %d spans, want %d", len(spans), tt.wantSpans)
+			}
+			last := spans[len(spans)-1]
+			if !last.Ended() || last.EndTime().Before(last.Start) {
+				t.Error("span not ended after the body was closed")
+			}
+			if got := last.Attribute("http.response.status_code"); got != tt.wantStatus {
+				t.Errorf("status attribute = %v, want %d", got, tt.wantStatus)
+			}
+			if got := last.Attribute("http.request.method"); got != "GET" {
+				t.Errorf("method attribute = %v", got)
+			}
+			if tt.wantError != "" && last.Attribute("error.type") != tt.wantError {
+				t.Errorf("error.type = %v, want %s", last.Attribute("error.type"), tt.wantError)
+			}
+			if tt.wantSpans > 1 && last.Attribute("http.request.resend_count") != tt.wantSpans-1 {
+				t.Errorf("resend_count = %v", last.Attribute("http.request.resend_count"))
+			}
+
+			for i, span := range spans {
+				sc := span.SpanContext()
+				want := "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-01"
+				if traceparents[i] != want {
+					t.Errorf("attempt %d traceparent = %q, want %q", i+1, traceparents[i], want)
+				}
+			}
+
+			var names []string
+			for _, e := range spans[0].Events() {
+				names = append(names, e.Name)
+			}
+			for _, want := range []string{"http.get_conn", "http.got_conn", "http.wrote_request", "http.first_byte"} {
+				if !slices.Contains(names, want) {
+					t.Errorf("events %v are missing %s", names, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTracingParentAndError(t *testing.T) {
+	tracer := &jellotest.Tracer{}
+	c, err := jello.NewClient("http://127.0.0.1:1", jello.WithMiddleware(jello.StageObserve, jello.Tracing(tracer)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, parent := tracer.Start(context.Background(), "list locations")
+	c.Locations.List(ctx)
+	parent.End()
+
+	spans := tracer.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	child := spans[1]
+	if child.Parent != parent.SpanContext().SpanID || child.SpanContext().TraceID != parent.SpanContext().TraceID {
+		t.Error("request span is not a child of the span in the context")
+	}
+	if len(child.Errors()) != 1 || !child.Ended() {
+		t.Errorf("errors = %v, ended = %v; want the dial error and an ended span", child.Errors(), child.Ended())
+	}
+	if child.Attribute("error.type") != "_OTHER" {
+		t.Errorf("error.type = %v", child.Attribute("error.type"))
+	}
+}
+
+func TestRecordMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/locations/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+
+	m := &jellotest.Metrics{}
+	c, err := jello.NewClient(srv.URL, jello.WithMiddleware(jello.StageObserve, jello.RecordMetrics(m)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		c.Locations.List(context.Background())
+	}
+	c.Locations.Get(context.Background(), "00000000-0000-4000-8000-000000000000")
+
+	host := strings.Split(strings.TrimPrefix(srv.URL, "http://"), ":")[0]
+	ok := []jello.Attribute{
+		{Key: "http.request.method", Value: "GET"},
+		{Key: "server.address", Value: host},
+		{Key: "http.response.status_code", Value: 200},
+	}
+	if got := m.Count(jello.MetricRequestCount, ok...); got != 3 {
+		t.Errorf("ok count = %d, want 3", got)
+	}
+	if h := m.Histogram(jello.MetricRequestDuration, ok...); h.Count != 3 || h.Sum <= 0 {
+		t.Errorf("ok histogram = %+v", h)
+	}
+	notFound := append(ok[:2:2],
+		jello.Attribute{Key: "http.response.status_code", Value: 404},
+		jello.Attribute{Key: "error.type", Value: "404"})
+	if got := m.Count(jello.MetricRequestCount, notFound...); got != 1 {
+		t.Errorf("404 count = %d, want 1", got)
+	}
+}
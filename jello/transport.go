@@ -0,0 +1,118 @@
+package jello
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// TransportConfig tunes the http.Transport a Client sends requests
+// through. One transport is built per Client and reused by every call,
+// so connections are kept alive between requests.
+type TransportConfig struct {
+	// MaxIdleConnsPerHost is how many idle connections are kept open per
+	// host. http.DefaultTransport keeps only 2, which forces concurrent
+	// callers to keep opening new connections.
+	MaxIdleConnsPerHost int
+	// MaxIdleConns caps idle connections across all hosts.
+	MaxIdleConns int
+	// IdleConnTimeout closes connections that have been idle this long.
+	IdleConnTimeout time.Duration
+	// DialTimeout limits how long the TCP connect may take.
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout limits how long the TLS handshake may take.
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout limits how long to wait for the response
+	// headers once the request has been written. 0 means no limit.
+	ResponseHeaderTimeout time.Duration
+	// DisableHTTP2 stops the transport from negotiating HTTP/2 over TLS.
+	DisableHTTP2 bool
+}
+
+// DefaultTransportConfig returns the settings NewClient uses.
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConnsPerHost:   32,
+		MaxIdleConns:          128,
+		IdleConnTimeout:       90 * time.Second,
+		DialTimeout:           5 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// WithTransportConfig replaces the default transport settings.
+func WithTransportConfig(cfg TransportConfig) Option {
+	return func(c *Client) {
+		c.transportConfig = cfg
+	}
+}
+
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// newTransport builds an http.Transport from cfg that opens connections
+// with dial. It uses the proxy from the environment; NewClient replaces
+// that with Client.proxyFunc.
+func newTransport(cfg TransportConfig, dial dialFunc) *http.Transport {
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dial,
+		ForceAttemptHTTP2:     !cfg.DisableHTTP2,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// ConnStats counts how requests got their connections.
+type ConnStats struct {
+	// Requests is the number of connections handed out, one per attempt.
+	Requests int64
+	// NewConns is how many of them had to be dialed.
+	NewConns int64
+	// ReusedConns is how many came from an earlier request.
+	ReusedConns int64
+	// IdleReused is how many of the reused ones were sitting idle in
+	// the pool, as opposed to being shared over HTTP/2.
+	IdleReused int64
+}
+
+type connStats struct {
+	requests, newConns, reused, idle atomic.Int64
+}
+
+func (s *connStats) snapshot() ConnStats {
+	return ConnStats{
+		Requests:    s.requests.Load(),
+		NewConns:    s.newConns.Load(),
+		ReusedConns: s.reused.Load(),
+		IdleReused:  s.idle.Load(),
+	}
+}
+
+func (s *connStats) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			s.requests.Add(1)
+			if !info.Reused {
+				s.newConns.Add(1)
+				return
+			}
+			s.reused.Add(1)
+			if info.WasIdle {
+				s.idle.Add(1)
+			}
+		},
+	}
+}
+
+// ConnStats reports connection reuse for requests made by c.
+func (c *Client) ConnStats() ConnStats {
+	return c.stats.snapshot()
+}
@@ -0,0 +1,137 @@
+package jello
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCountingServer serves a small location list and counts the
+// connections clients open to it.
+func newCountingServer(tb testing.TB) (*httptest.Server, *atomic.Int64) {
+	var dialed atomic.Int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `[{"id":"52fdfc07-2182-454f-963f-5f0f9a621d72","name":"Gondor"}]`)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			dialed.Add(1)
+		}
+	}
+	srv.Start()
+	tb.Cleanup(srv.Close)
+	return srv, &dialed
+}
+
+func TestSharedTransportReusesConnections(t *testing.T) {
+	srv, dialed := newCountingServer(t)
+	c, err := NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers, calls = 8, 20
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range calls {
+				if _, err := c.Locations.List(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := c.ConnStats()
+	if stats.Requests != workers*calls {
+		t.Errorf("Requests = %d, want %d", stats.Requests, workers*calls)
+	}
+	if stats.NewConns > workers || dialed.Load() > workers {
+		t.Errorf("dialed %d connections (stats say %d) for %d workers", dialed.Load(), stats.NewConns, workers)
+	}
+	if stats.NewConns+stats.ReusedConns != stats.Requests {
+		t.Errorf("NewConns %d + ReusedConns %d != Requests %d", stats.NewConns, stats.ReusedConns, stats.Requests)
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	cfg := DefaultTransportConfig()
+	cfg.MaxIdleConnsPerHost = 3
+	cfg.DisableHTTP2 = true
+	tr := newTransport(cfg, nil)
+	if tr.MaxIdleConnsPerHost != 3 || tr.ForceAttemptHTTP2 {
+		t.Errorf("MaxIdleConnsPerHost = %d, ForceAttemptHTTP2 = %v", tr.MaxIdleConnsPerHost, tr.ForceAttemptHTTP2)
+	}
+	if tr.ResponseHeaderTimeout != cfg.ResponseHeaderTimeout || tr.IdleConnTimeout != cfg.IdleConnTimeout {
+		t.Errorf("timeouts not copied from %+v", cfg)
+	}
+}
+
+// BenchmarkClient compares the per-call http.Client pattern from the
+// methods chapter with a jello.Client that reuses one tuned transport:
+//
+//	go test -bench Client -benchtime 2s ./jello
+func BenchmarkClient(b *testing.B) {
+	b.Run("per-call http.Client", func(b *testing.B) {
+		srv, dialed := newCountingServer(b)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if err := getPerCall(srv.URL + "/locations"); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+		b.ReportMetric(float64(dialed.Load()), "conns")
+	})
+	b.Run("shared jello.Client", func(b *testing.B) {
+		srv, dialed := newCountingServer(b)
+		c, err := NewClient(srv.URL)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := c.Locations.List(context.Background()); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+		b.ReportMetric(float64(dialed.Load()), "conns")
+	})
+}
+
+// getPerCall is the pattern from the chapters: a new http.Client for
+// every request and an early return that leaves the body unread.
+func getPerCall(url string) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		Timeout: time.Second * 10,
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return fmt.Errorf("status %d", res.StatusCode)
+	}
+	return nil
+}
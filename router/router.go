@@ -0,0 +1,159 @@
+// Package router maps request paths to handlers, the server side of the
+// paths chapter: a path can carry the API version, the type of resource
+// and parameters like its ID.
+//
+// It is a thin layer over http.ServeMux patterns, which already match
+// methods and {name} wildcards. On top it adds version prefixes through
+// Group, middleware, and JSON 404 and 405 responses:
+//
+//	r := router.New()
+//	v1 := r.Group("/v1")
+//	v1.HandleFunc(http.MethodGet, "/projects/{id}", func(w http.ResponseWriter, req *http.Request) {
+//		id := req.PathValue("id")
+//		...
+//	})
+//	http.ListenAndServe(":8080", r)
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Middleware wraps a handler.
+type Middleware func(http.Handler) http.Handler
+
+// Router dispatches requests by method and path.
+type Router struct {
+	// NotFound handles requests no route matches. Defaults to a JSON
+	// 404. Only used on the router from New.
+	NotFound http.Handler
+	// MethodNotAllowed handles requests whose path matches a route but
+	// not with their method. The Allow header is already set when it
+	// runs. Defaults to a JSON 405. Only used on the router from New.
+	MethodNotAllowed http.Handler
+
+	mux        *http.ServeMux
+	root       *Router
+	prefix     string
+	middleware []Middleware
+}
+
+// New returns an empty router.
+func New() *Router {
+	r := &Router{mux: http.NewServeMux()}
+	r.root = r
+	return r
+}
+
+// Group returns a router whose routes are under prefix, e.g. "/v1", and
+// which starts with this router's middleware. Routes added to it are
+// served by this router.
+func (r *Router) Group(prefix string) *Router {
+	return &Router{
+		mux:        r.mux,
+		root:       r.root,
+		prefix:     r.prefix + strings.TrimSuffix(prefix, "/"),
+		middleware: slices.Clone(r.middleware),
+	}
+}
+
+// Use adds middleware to routes added after it, the first outermost.
+func (r *Router) Use(mws ...Middleware) {
+	r.middleware = append(r.middleware, mws...)
+}
+
+// Handle routes method requests for pattern to h. pattern is a
+// ServeMux path pattern such as "/issues/{id}" or "/files/{path...}";
+// a trailing slash matches everything below it. An empty method
+// matches every method. GET routes also answer HEAD.
+func (r *Router) Handle(method, pattern string, h http.Handler) {
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		h = r.middleware[i](h)
+	}
+	full := r.prefix + pattern
+	if method != "" {
+		full = method + " " + full
+	}
+	r.mux.Handle(full, h)
+}
+
+// HandleFunc is Handle for a function.
+func (r *Router) HandleFunc(method, pattern string, h http.HandlerFunc) {
+	r.Handle(method, pattern, h)
+}
+
+// ServeHTTP dispatches req.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	root := r.root
+	h, pattern := root.mux.Handler(req)
+	if pattern != "" {
+		root.mux.ServeHTTP(w, req)
+		return
+	}
+
+	// ServeMux answers unmatched requests itself, with a 404, a 405 or
+	// a redirect to a cleaned path. Keep redirects, and replace the
+	// plain text errors with the router's handlers.
+	rec := &recorder{header: http.Header{}}
+	h.ServeHTTP(rec, req)
+	switch rec.status {
+	case http.StatusNotFound:
+		handlerOr(root.NotFound, notFound).ServeHTTP(w, req)
+	case http.StatusMethodNotAllowed:
+		w.Header()["Allow"] = rec.header["Allow"]
+		handlerOr(root.MethodNotAllowed, methodNotAllowed).ServeHTTP(w, req)
+	default:
+		root.mux.ServeHTTP(w, req)
+	}
+}
+
+func handlerOr(h http.Handler, fallback http.HandlerFunc) http.Handler {
+	if h != nil {
+		return h
+	}
+	return fallback
+}
+
+func notFound(w http.ResponseWriter, req *http.Request) {
+	WriteError(w, http.StatusNotFound, "no route for "+req.URL.Path)
+}
+
+func methodNotAllowed(w http.ResponseWriter, req *http.Request) {
+	WriteError(w, http.StatusMethodNotAllowed, req.Method+" is not allowed for "+req.URL.Path)
+}
+
+// recorder captures what ServeMux's fallback handlers write.
+type recorder struct {
+	header http.Header
+	status int
+}
+
+func (r *recorder) Header() http.Header { return r.header }
+
+func (r *recorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return len(p), nil
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// WriteJSON writes v as a JSON response with the given status.
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// WriteError writes a JSON error response, {"error": message}.
+func WriteError(w http.ResponseWriter, status int, message string) {
+	WriteJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,184 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// tag is middleware that appends name to the X-Trace response header.
+func tag(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func newTestRouter() *Router {
+	r := New()
+	r.Use(tag("root"))
+	r.HandleFunc(http.MethodGet, "/health", func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "ok")
+	})
+	v1 := r.Group("/v1/")
+	v1.Use(tag("v1"))
+	v1.HandleFunc(http.MethodGet, "/projects/{id}", func(w http.ResponseWriter, req *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{"project": req.PathValue("id")})
+	})
+	v1.HandleFunc(http.MethodDelete, "/projects/{id}", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	v1.HandleFunc(http.MethodGet, "/files/{path...}", func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, req.PathValue("path"))
+	})
+	v1.HandleFunc("", "/echo", func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, req.Method)
+	})
+	v2 := r.Group("/v2")
+	v2.HandleFunc(http.MethodGet, "/projects/{id}", func(w http.ResponseWriter, req *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{"id": req.PathValue("id")})
+	})
+	v2.HandleFunc(http.MethodGet, "/docs/", func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "docs "+req.URL.Path)
+	})
+	return r
+}
+
+func TestRouter(t *testing.T) {
+	tests := []struct {
+		method, path string
+		wantStatus   int // 0 for any redirect, whose code depends on the Go version
+		wantBody     string
+		wantTrace    string
+		wantAllow    string
+		wantLocation string
+	}{
+		{method: "GET", path: "/health", wantStatus: 200, wantBody: "ok", wantTrace: "root"},
+		{method: "GET", path: "/v1/projects/42", wantStatus: 200, wantBody: `{"project":"42"}`, wantTrace: "root,v1"},
+		{method: "GET", path: "/v1/projects/a%2Fb", wantStatus: 200, wantBody: `{"project":"a/b"}`, wantTrace: "root,v1"},
+		{method: "HEAD", path: "/v1/projects/42", wantStatus: 200, wantTrace: "root,v1"},
+		{method: "DELETE", path: "/v1/projects/42", wantStatus: 204, wantTrace: "root,v1"},
+		{method: "GET", path: "/v1/files/docs/a.txt", wantStatus: 200, wantBody: "docs/a.txt", wantTrace: "root,v1"},
+		{method: "PATCH", path: "/v1/echo", wantStatus: 200, wantBody: "PATCH", wantTrace: "root,v1"},
+		{method: "GET", path: "/v2/projects/7", wantStatus: 200, wantBody: `{"id":"7"}`, wantTrace: "root"},
+		{method: "GET", path: "/v2/docs/a/b", wantStatus: 200, wantBody: "docs /v2/docs/a/b", wantTrace: "root"},
+
+		{method: "GET", path: "/v2/docs", wantLocation: "/v2/docs/"},
+		{method: "GET", path: "/v1//projects/42", wantLocation: "/v1/projects/42"},
+
+		{method: "GET", path: "/v3/projects/42", wantStatus: 404, wantBody: `{"error":"no route for /v3/projects/42"}`},
+		{method: "GET", path: "/v1/projects", wantStatus: 404, wantBody: `{"error":"no route for /v1/projects"}`},
+		{method: "GET", path: "/v1/projects/42/extra", wantStatus: 404, wantBody: `{"error":"no route for /v1/projects/42/extra"}`},
+		{
+			method: "POST", path: "/v1/projects/42", wantStatus: 405,
+			wantBody:  `{"error":"POST is not allowed for /v1/projects/42"}`,
+			wantAllow: "DELETE, GET, HEAD",
+		},
+		{
+			method: "PUT", path: "/health", wantStatus: 405,
+			wantBody:  `{"error":"PUT is not allowed for /health"}`,
+			wantAllow: "GET, HEAD",
+		},
+	}
+	srv := httptest.NewServer(newTestRouter())
+	defer srv.Close()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, srv.URL+tt.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		name := tt.method + " " + tt.path
+		if tt.wantStatus == 0 && res.StatusCode/100 != 3 {
+			t.Errorf("%s: status = %d, want a redirect", name, res.StatusCode)
+		} else if tt.wantStatus != 0 && res.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", name, res.StatusCode, tt.wantStatus)
+		}
+		if tt.wantBody != "" && strings.TrimSpace(string(body)) != tt.wantBody {
+			t.Errorf("%s: body = %q, want %q", name, body, tt.wantBody)
+		}
+		if tt.method == http.MethodHead && len(body) != 0 {
+			t.Errorf("%s: got a body %q", name, body)
+		}
+		if got := strings.Join(res.Header.Values("X-Trace"), ","); got != tt.wantTrace {
+			t.Errorf("%s: middleware = %q, want %q", name, got, tt.wantTrace)
+		}
+		if got := res.Header.Get("Allow"); got != tt.wantAllow {
+			t.Errorf("%s: Allow = %q, want %q", name, got, tt.wantAllow)
+		}
+		if got := res.Header.Get("Location"); got != tt.wantLocation {
+			t.Errorf("%s: Location = %q, want %q", name, got, tt.wantLocation)
+		}
+		if tt.wantStatus >= 400 {
+			if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("%s: content type = %q, want application/json", name, ct)
+			}
+			var e struct{ Error string }
+			if err := json.Unmarshal(body, &e); err != nil || e.Error == "" {
+				t.Errorf("%s: body %q is not a JSON error", name, body)
+			}
+		}
+	}
+}
+
+func TestRouterCustomErrors(t *testing.T) {
+	r := newTestRouter()
+	r.NotFound = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		WriteError(w, http.StatusNotFound, "nothing here")
+	})
+	r.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		WriteError(w, http.StatusMethodNotAllowed, "use "+w.Header().Get("Allow"))
+	})
+	tests := []struct {
+		method, path string
+		wantStatus   int
+		wantBody     string
+	}{
+		{method: "GET", path: "/nope", wantStatus: 404, wantBody: `{"error":"nothing here"}`},
+		{method: "POST", path: "/health", wantStatus: 405, wantBody: `{"error":"use GET, HEAD"}`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.wantStatus || strings.TrimSpace(w.Body.String()) != tt.wantBody {
+			t.Errorf("%s %s: got %d %q, want %d %q", tt.method, tt.path, w.Code, w.Body, tt.wantStatus, tt.wantBody)
+		}
+	}
+}
+
+func TestGroupMiddlewareIsCopied(t *testing.T) {
+	r := New()
+	r.Use(tag("a"))
+	g := r.Group("/g")
+	r.Use(tag("later"))
+	g.Use(tag("b"))
+	g.HandleFunc(http.MethodGet, "/x", func(w http.ResponseWriter, req *http.Request) {})
+	r.HandleFunc(http.MethodGet, "/y", func(w http.ResponseWriter, req *http.Request) {})
+
+	tests := []struct {
+		path, want string
+	}{
+		{path: "/g/x", want: "a,b"},
+		{path: "/y", want: "a,later"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got := strings.Join(w.Header().Values("X-Trace"), ","); got != tt.want {
+			t.Errorf("%s: middleware = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
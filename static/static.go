@@ -0,0 +1,267 @@
+// Package static serves a directory over HTTP, the simple mapping from
+// the paths chapter where a GET for /documents/hello.txt returns the
+// file at documents/hello.txt under the served directory.
+//
+// Files are opened through an os.Root, so neither ".." in a path nor a
+// symlink can reach outside the directory. Responses carry
+// Last-Modified and ETag validators, and http.ServeContent handles
+// conditional and range requests.
+package static
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options configures a Server. The zero value serves index.html for
+// directories, hides dotfiles and doesn't list directories.
+type Options struct {
+	// Index lists file names served for a directory, in order. nil means
+	// index.html; an empty slice turns index files off.
+	Index []string
+	// ListDirectories lists directories that have no index file, as HTML
+	// or, if the request accepts application/json, as a JSON array of
+	// Entry. cmd/mirror uses the JSON listing.
+	ListDirectories bool
+	// ServeDotFiles serves files and directories whose names start with
+	// a dot, such as .git or .env. They are 404s by default.
+	ServeDotFiles bool
+	// MaxAge sets Cache-Control: max-age. 0 leaves it out, so clients
+	// revalidate with If-None-Match or If-Modified-Since.
+	MaxAge time.Duration
+}
+
+// Entry is one item of a JSON directory listing.
+type Entry struct {
+	Name    string    `json:"name"`
+	Dir     bool      `json:"dir,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Server serves files under a directory.
+type Server struct {
+	root *os.Root
+	opts Options
+}
+
+// New returns a Server for dir. Close it to release the directory.
+func New(dir string, opts *Options) (*Server, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", dir, err)
+	}
+	s := &Server{root: root}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Index == nil {
+		s.opts.Index = []string{"index.html"}
+	}
+	return s, nil
+}
+
+// Close releases the served directory.
+func (s *Server) Close() error {
+	return s.root.Close()
+}
+
+// ServeHTTP serves the file or directory at the request path. Mount it
+// under a prefix with http.StripPrefix.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name, ok := s.fileName(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := s.root.Open(name)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	if !info.IsDir() {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			redirect(w, r, "../"+lastSegment(r))
+			return
+		}
+		s.serveFile(w, r, f, info)
+		return
+	}
+
+	// Relative links in an index page or listing only work from a path
+	// that ends in a slash.
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		redirect(w, r, lastSegment(r)+"/")
+		return
+	}
+	for _, index := range s.opts.Index {
+		f, err := s.root.Open(path.Join(name, index))
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		if info, err := f.Stat(); err == nil && info.Mode().IsRegular() {
+			s.serveFile(w, r, f, info)
+			return
+		}
+	}
+	if !s.opts.ListDirectories {
+		http.NotFound(w, r)
+		return
+	}
+	s.list(w, r, f)
+}
+
+// fileName turns a URL path into a name for the os.Root, rejecting
+// dotfiles unless they are allowed. os.Root rejects anything that
+// would leave the directory, but cleaning first keeps ".." out of
+// error messages and logs.
+func (s *Server) fileName(urlPath string) (string, bool) {
+	if strings.ContainsAny(urlPath, "\x00\\") {
+		return "", false
+	}
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		return ".", true
+	}
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") && !s.opts.ServeDotFiles {
+			return "", false
+		}
+	}
+	return name, true
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request, f *os.File, info fs.FileInfo) {
+	if !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+	h := w.Header()
+	h.Set("ETag", etag(info))
+	h.Set("X-Content-Type-Options", "nosniff")
+	if s.opts.MaxAge > 0 {
+		h.Set("Cache-Control", "max-age="+strconv.Itoa(int(s.opts.MaxAge.Seconds())))
+	}
+	if ctype := mime.TypeByExtension(filepath.Ext(info.Name())); ctype != "" {
+		h.Set("Content-Type", ctype)
+	}
+	// ServeContent handles If-None-Match, If-Modified-Since, If-Range
+	// and Range, and sniffs the type of files without an extension.
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// etag is a strong validator from the size and modification time, the
+// same inputs nginx uses, so it needs no hashing of the content.
+func etag(info fs.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, dir *os.File) {
+	dirEntries, err := dir.ReadDir(-1)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	entries := []Entry{}
+	for _, d := range dirEntries {
+		if strings.HasPrefix(d.Name(), ".") && !s.opts.ServeDotFiles {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			continue
+		}
+		entries = append(entries, Entry{
+			Name:    d.Name(),
+			Dir:     info.IsDir(),
+			Size:    info.Size(),
+			ModTime: info.ModTime().UTC(),
+		})
+	}
+	slices.SortFunc(entries, func(a, b Entry) int { return strings.Compare(a.Name, b.Name) })
+
+	w.Header().Set("Vary", "Accept")
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	listingTemplate.Execute(w, struct {
+		Path    string
+		Entries []Entry
+	}{r.URL.Path, entries})
+}
+
+var listingTemplate = template.Must(template.New("listing").Parse(`<!doctype html>
+<title>{{.Path}}</title>
+<h1>{{.Path}}</h1>
+<ul>
+{{range .Entries}}<li><a href="{{.Name}}{{if .Dir}}/{{end}}">{{.Name}}{{if .Dir}}/{{end}}</a></li>
+{{end}}</ul>
+`))
+
+func (s *Server) error(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.NotFound(w, r)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, "forbidden", http.StatusForbidden)
+	default:
+		// Including paths that os.Root refused because they lead
+		// outside the directory.
+		http.NotFound(w, r)
+	}
+}
+
+// redirect sends a permanent redirect to target, keeping the query.
+// The Location header is left relative: http.Redirect would resolve it
+// against r.URL.Path, which http.StripPrefix has already shortened.
+// Being relative also means a path like "//example.com" can't become a
+// redirect to another site.
+func redirect(w http.ResponseWriter, r *http.Request, target string) {
+	u := url.URL{Path: target, RawQuery: r.URL.RawQuery}
+	w.Header().Set("Location", u.String())
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+// lastSegment returns the last segment of the path the client asked
+// for, before any http.StripPrefix, which relative redirects are
+// resolved against.
+func lastSegment(r *http.Request) string {
+	p := r.URL.Path
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		p = u.Path
+	}
+	return path.Base(p)
+}
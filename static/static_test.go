@@ -0,0 +1,332 @@
+package static
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTree writes files, keyed by slash-separated path, under a new
+// directory and returns it.
+func newTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// newTestServer serves dir at the root and under /files/.
+func newTestServer(t *testing.T, dir string, opts *Options) *httptest.Server {
+	t.Helper()
+	s, err := New(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	mux := http.NewServeMux()
+	mux.Handle("/", s)
+	mux.Handle("/files/", http.StripPrefix("/files", s))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// noRedirects is a client that returns redirects instead of following
+// them.
+var noRedirects = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}}
+
+func get(t *testing.T, rawURL string, header http.Header) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := noRedirects.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res, string(body)
+}
+
+func TestServer(t *testing.T) {
+	dir := newTree(t, map[string]string{
+		"hello.txt":            "hello",
+		"docs/index.html":      "<p>docs</p>",
+		"docs/a b.txt":         "spaces",
+		"docs/guide/intro.md":  "# intro",
+		"docs/guide/setup.txt": "setup",
+		"noindex/file.txt":     "x",
+		".env":                 "SECRET=1",
+		".git/config":          "[core]",
+	})
+	srv := newTestServer(t, dir, &Options{ListDirectories: true})
+
+	tests := []struct {
+		path         string
+		header       http.Header
+		wantStatus   int
+		wantBody     string
+		wantType     string
+		wantLocation string
+	}{
+		{path: "/hello.txt", wantStatus: 200, wantBody: "hello", wantType: "text/plain; charset=utf-8"},
+		{path: "/docs/", wantStatus: 200, wantBody: "<p>docs</p>", wantType: "text/html; charset=utf-8"},
+		{path: "/docs/a%20b.txt", wantStatus: 200, wantBody: "spaces"},
+		{path: "/files/hello.txt", wantStatus: 200, wantBody: "hello"},
+		{path: "/files/docs/guide/intro.md", wantStatus: 200, wantBody: "# intro"},
+
+		// Directories redirect to their path with a slash, and files
+		// to theirs without, relative to what the client asked for.
+		{path: "/docs", wantStatus: 301, wantLocation: "docs/"},
+		{path: "/docs?x=1", wantStatus: 301, wantLocation: "docs/?x=1"},
+		{path: "/files/docs", wantStatus: 301, wantLocation: "docs/"},
+		{path: "/files/docs/guide", wantStatus: 301, wantLocation: "guide/"},
+		{path: "/hello.txt/", wantStatus: 301, wantLocation: "../hello.txt"},
+		{path: "/files/docs/guide/intro.md/", wantStatus: 301, wantLocation: "../intro.md"},
+
+		{path: "/missing.txt", wantStatus: 404},
+		{path: "/.env", wantStatus: 404},
+		{path: "/.git/config", wantStatus: 404},
+		{path: "/files/..%2f..%2fetc/passwd", wantStatus: 404},
+		{path: "/docs/guide/setup.txt%00", wantStatus: 404},
+	}
+	for _, tt := range tests {
+		res, body := get(t, srv.URL+tt.path, tt.header)
+		if res.StatusCode != tt.wantStatus {
+			t.Errorf("GET %s: status = %d, want %d", tt.path, res.StatusCode, tt.wantStatus)
+			continue
+		}
+		if tt.wantBody != "" && body != tt.wantBody {
+			t.Errorf("GET %s: body = %q, want %q", tt.path, body, tt.wantBody)
+		}
+		if tt.wantType != "" && res.Header.Get("Content-Type") != tt.wantType {
+			t.Errorf("GET %s: content type = %q, want %q", tt.path, res.Header.Get("Content-Type"), tt.wantType)
+		}
+		if got := res.Header.Get("Location"); got != tt.wantLocation {
+			t.Errorf("GET %s: Location = %q, want %q", tt.path, got, tt.wantLocation)
+		}
+	}
+}
+
+// TestRedirectStripPrefix follows the directory redirects under
+// http.StripPrefix to check they land back under the prefix.
+func TestRedirectStripPrefix(t *testing.T) {
+	dir := newTree(t, map[string]string{"docs/index.html": "docs", "a.txt": "a"})
+	srv := newTestServer(t, dir, nil)
+	tests := []struct {
+		path, wantPath, wantBody string
+	}{
+		{path: "/files/docs", wantPath: "/files/docs/", wantBody: "docs"},
+		{path: "/files/a.txt/", wantPath: "/files/a.txt", wantBody: "a"},
+		{path: "/docs", wantPath: "/docs/", wantBody: "docs"},
+	}
+	for _, tt := range tests {
+		res, err := http.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.Request.URL.Path != tt.wantPath || string(body) != tt.wantBody {
+			t.Errorf("GET %s: ended at %s with %q, want %s with %q", tt.path, res.Request.URL.Path, body, tt.wantPath, tt.wantBody)
+		}
+	}
+}
+
+func TestListing(t *testing.T) {
+	files := map[string]string{
+		"b.txt":     "bb",
+		"a.txt":     "a",
+		"sub/c.txt": "c",
+		".hidden":   "h",
+	}
+	dir := newTree(t, files)
+	tests := []struct {
+		name       string
+		opts       *Options
+		wantStatus int
+		wantNames  []string
+	}{
+		{name: "off by default", wantStatus: 404},
+		{name: "listed", opts: &Options{ListDirectories: true}, wantStatus: 200, wantNames: []string{"a.txt", "b.txt", "sub"}},
+		{name: "dotfiles", opts: &Options{ListDirectories: true, ServeDotFiles: true}, wantStatus: 200, wantNames: []string{".hidden", "a.txt", "b.txt", "sub"}},
+	}
+	for _, tt := range tests {
+		srv := newTestServer(t, dir, tt.opts)
+		res, body := get(t, srv.URL+"/", http.Header{"Accept": {"application/json"}})
+		if res.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, res.StatusCode, tt.wantStatus)
+			continue
+		}
+		if tt.wantStatus != 200 {
+			continue
+		}
+		var entries []Entry
+		if err := json.Unmarshal([]byte(body), &entries); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+			if e.Dir != (e.Name == "sub") || !e.Dir && e.Size != int64(len(files[e.Name])) {
+				t.Errorf("%s: entry %+v", tt.name, e)
+			}
+		}
+		if strings.Join(names, ",") != strings.Join(tt.wantNames, ",") {
+			t.Errorf("%s: names = %q, want %q", tt.name, names, tt.wantNames)
+		}
+		if res.Header.Get("Vary") != "Accept" {
+			t.Errorf("%s: Vary = %q", tt.name, res.Header.Get("Vary"))
+		}
+
+		res, body = get(t, srv.URL+"/", nil)
+		if ct := res.Header.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+			t.Errorf("%s: HTML listing content type = %q", tt.name, ct)
+		}
+		if !strings.Contains(body, `<a href="sub/">sub/</a>`) || !strings.Contains(body, `<a href="a.txt">a.txt</a>`) {
+			t.Errorf("%s: HTML listing:\n%s", tt.name, body)
+		}
+	}
+}
+
+func TestIndexOptions(t *testing.T) {
+	dir := newTree(t, map[string]string{"index.html": "html", "index.txt": "txt"})
+	tests := []struct {
+		name       string
+		index      []string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "default", wantStatus: 200, wantBody: "html"},
+		{name: "order", index: []string{"missing", "index.txt", "index.html"}, wantStatus: 200, wantBody: "txt"},
+		{name: "off", index: []string{}, wantStatus: 404},
+	}
+	for _, tt := range tests {
+		srv := newTestServer(t, dir, &Options{Index: tt.index})
+		res, body := get(t, srv.URL+"/", nil)
+		if res.StatusCode != tt.wantStatus || tt.wantBody != "" && body != tt.wantBody {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, res.StatusCode, body, tt.wantStatus, tt.wantBody)
+		}
+	}
+}
+
+func TestConditional(t *testing.T) {
+	dir := newTree(t, map[string]string{"data.txt": "0123456789"})
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "data.txt"), modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t, dir, &Options{MaxAge: time.Hour})
+	res, _ := get(t, srv.URL+"/data.txt", nil)
+	etag := res.Header.Get("ETag")
+	if etag == "" || res.Header.Get("Last-Modified") != modTime.Format(http.TimeFormat) {
+		t.Fatalf("validators: ETag %q, Last-Modified %q", etag, res.Header.Get("Last-Modified"))
+	}
+	if got := res.Header.Get("Cache-Control"); got != "max-age=3600" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "if-none-match", header: http.Header{"If-None-Match": {etag}}, wantStatus: 304},
+		{name: "stale etag", header: http.Header{"If-None-Match": {`"other"`}}, wantStatus: 200, wantBody: "0123456789"},
+		{name: "if-modified-since", header: http.Header{"If-Modified-Since": {modTime.Format(http.TimeFormat)}}, wantStatus: 304},
+		{name: "modified since", header: http.Header{"If-Modified-Since": {modTime.Add(-time.Hour).Format(http.TimeFormat)}}, wantStatus: 200, wantBody: "0123456789"},
+		{name: "range", header: http.Header{"Range": {"bytes=2-4"}}, wantStatus: 206, wantBody: "234"},
+		{name: "if-range stale", header: http.Header{"Range": {"bytes=2-4"}, "If-Range": {`"other"`}}, wantStatus: 200, wantBody: "0123456789"},
+	}
+	for _, tt := range tests {
+		res, body := get(t, srv.URL+"/data.txt", tt.header)
+		if res.StatusCode != tt.wantStatus || body != tt.wantBody {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, res.StatusCode, body, tt.wantStatus, tt.wantBody)
+		}
+	}
+}
+
+func TestMethods(t *testing.T) {
+	srv := newTestServer(t, newTree(t, map[string]string{"a.txt": "a"}), nil)
+	tests := []struct {
+		method     string
+		wantStatus int
+	}{
+		{http.MethodGet, 200},
+		{http.MethodHead, 200},
+		{http.MethodPost, 405},
+		{http.MethodDelete, 405},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, srv.URL+"/a.txt", nil)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.method, res.StatusCode, tt.wantStatus)
+		}
+		if tt.wantStatus == 405 && res.Header.Get("Allow") != "GET, HEAD" {
+			t.Errorf("%s: Allow = %q", tt.method, res.Header.Get("Allow"))
+		}
+	}
+}
+
+// TestTraversal sends paths that a client or ServeMux would have
+// cleaned straight to the handler.
+func TestTraversal(t *testing.T) {
+	outside := newTree(t, map[string]string{"secret.txt": "secret"})
+	dir := filepath.Join(outside, "public")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, p := range []string{"/../secret.txt", "../secret.txt", "/a/../../secret.txt", `/..\secret.txt`} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.URL.Path = p
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: got %d %q, want 404", p, w.Code, w.Body)
+		}
+	}
+}
+
+func TestSymlinkOutside(t *testing.T) {
+	outside := newTree(t, map[string]string{"secret.txt": "secret"})
+	dir := newTree(t, map[string]string{"a.txt": "a"})
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(dir, "link.txt")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	srv := newTestServer(t, dir, nil)
+	if res, body := get(t, srv.URL+"/link.txt", nil); res.StatusCode != 404 {
+		t.Errorf("symlink out of the directory: got %d %q, want 404", res.StatusCode, body)
+	}
+}
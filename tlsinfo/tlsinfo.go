@@ -0,0 +1,218 @@
+// Package tlsinfo connects to a server and reports what it proved about
+// itself in the TLS handshake: the certificate chain the HTTPS chapter
+// says identifies the server, and the connection parameters agreed on.
+package tlsinfo
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+)
+
+// DefaultWarnWithin is how close to expiry a certificate gets a warning.
+const DefaultWarnWithin = 30 * 24 * time.Hour
+
+// Options configures Inspect. The zero value is ready to use.
+type Options struct {
+	// ServerName is sent for SNI and checked against the certificate.
+	// Defaults to the host being inspected.
+	ServerName string
+	// RootCAs verifies the chain. Defaults to the system roots.
+	RootCAs *x509.CertPool
+	// ALPN lists the protocols to offer. Defaults to h2 and http/1.1.
+	ALPN []string
+	// WarnWithin warns about certificates expiring within this long.
+	// Defaults to DefaultWarnWithin. A negative value only warns about
+	// certificates that have already expired.
+	WarnWithin time.Duration
+	// Now is the time certificates are checked at. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Certificate describes one certificate in the chain.
+type Certificate struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SANs               []string  `json:"sans,omitempty"`
+	SerialNumber       string    `json:"serial_number"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	DaysLeft           int       `json:"days_left"`
+	KeyType            string    `json:"key_type"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	IsCA               bool      `json:"is_ca"`
+	// SPKIHash is the value to pin with jello.TLSConfig.Pins.
+	SPKIHash string `json:"spki_sha256"`
+}
+
+// Report is what Inspect found. The chain is reported even when it
+// doesn't verify; the reason is in VerifyError.
+type Report struct {
+	Host        string        `json:"host"`
+	Addr        string        `json:"addr"`
+	ServerName  string        `json:"server_name"`
+	Version     string        `json:"version"`
+	CipherSuite string        `json:"cipher_suite"`
+	ALPN        string        `json:"alpn,omitempty"`
+	Verified    bool          `json:"verified"`
+	VerifyError string        `json:"verify_error,omitempty"`
+	Chain       []Certificate `json:"chain"`
+	Warnings    []string      `json:"warnings,omitempty"`
+}
+
+// HostPort returns the host:port to connect to for target, which may be
+// a URL, host:port or a bare host name. The port defaults to 443.
+func HostPort(target string) (string, error) {
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", fmt.Errorf("error parsing url: %w", err)
+		}
+		if u.Hostname() == "" {
+			return "", fmt.Errorf("url %q has no host", target)
+		}
+		port := u.Port()
+		if port == "" {
+			port = "443"
+		}
+		return net.JoinHostPort(u.Hostname(), port), nil
+	}
+	if host, port, err := net.SplitHostPort(target); err == nil {
+		return net.JoinHostPort(host, port), nil
+	}
+	return net.JoinHostPort(strings.Trim(target, "[]"), "443"), nil
+}
+
+// Inspect does a TLS handshake with target and reports on it. It only
+// returns an error if the handshake couldn't be done at all, so it can
+// be used on servers with broken certificates.
+func Inspect(ctx context.Context, target string, opts *Options) (*Report, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	addr, err := HostPort(target)
+	if err != nil {
+		return nil, err
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	serverName := opts.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+	alpn := opts.ALPN
+	if alpn == nil {
+		alpn = []string{"h2", "http/1.1"}
+	}
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+	warnWithin := opts.WarnWithin
+	if warnWithin == 0 {
+		warnWithin = DefaultWarnWithin
+	}
+
+	// Verification is done below instead of in the handshake, so a bad
+	// chain is still reported instead of just failing.
+	d := &tls.Dialer{Config: &tls.Config{
+		ServerName:         serverName,
+		NextProtos:         alpn,
+		InsecureSkipVerify: true,
+	}}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", addr, err)
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+
+	report := &Report{
+		Host:        host,
+		Addr:        conn.RemoteAddr().String(),
+		ServerName:  serverName,
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ALPN:        state.NegotiatedProtocol,
+	}
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("%s presented no certificates", addr)
+	}
+
+	t := now()
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         opts.RootCAs,
+		Intermediates: intermediates,
+		CurrentTime:   t,
+	})
+	report.Verified = err == nil
+	if err != nil {
+		report.VerifyError = err.Error()
+		report.Warnings = append(report.Warnings, "chain does not verify: "+err.Error())
+	}
+
+	for i, cert := range state.PeerCertificates {
+		c := describe(cert, t)
+		report.Chain = append(report.Chain, c)
+		left := cert.NotAfter.Sub(t)
+		switch {
+		case left < 0:
+			report.Warnings = append(report.Warnings,
+				fmt.Sprintf("certificate %d (%s) expired on %s", i, c.Subject, cert.NotAfter.Format(time.DateOnly)))
+		case left < warnWithin:
+			report.Warnings = append(report.Warnings,
+				fmt.Sprintf("certificate %d (%s) expires in %d days, on %s", i, c.Subject, c.DaysLeft, cert.NotAfter.Format(time.DateOnly)))
+		}
+	}
+	return report, nil
+}
+
+func describe(cert *x509.Certificate, now time.Time) Certificate {
+	c := Certificate{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       fmt.Sprintf("%X", cert.SerialNumber),
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		DaysLeft:           int(cert.NotAfter.Sub(now).Hours() / 24),
+		KeyType:            keyType(cert),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		IsCA:               cert.IsCA,
+		SPKIHash:           jello.SPKIHash(cert),
+	}
+	c.SANs = append(c.SANs, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		c.SANs = append(c.SANs, ip.String())
+	}
+	c.SANs = append(c.SANs, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		c.SANs = append(c.SANs, u.String())
+	}
+	return c
+}
+
+func keyType(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return cert.PublicKeyAlgorithm.String()
+}
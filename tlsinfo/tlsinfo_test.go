@@ -0,0 +1,185 @@
+package tlsinfo
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JavierLU90/http_clients_go/jello"
+	"github.com/JavierLU90/http_clients_go/jello/jellotest"
+)
+
+func TestHostPort(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "api.jello.com", want: "api.jello.com:443"},
+		{in: "api.jello.com:8443", want: "api.jello.com:8443"},
+		{in: "https://boot.dev/courses", want: "boot.dev:443"},
+		{in: "https://boot.dev:8443", want: "boot.dev:8443"},
+		{in: "::1", want: "[::1]:443"},
+		{in: "[::1]", want: "[::1]:443"},
+		{in: "[::1]:8443", want: "[::1]:8443"},
+		{in: "https://", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := HostPort(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("HostPort(%q) = %q, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("HostPort(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// newServer starts a TLS server presenting cert.
+func newServer(t *testing.T, cert tls.Certificate) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	// Inspect hangs up after the handshake, which the server logs.
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestInspect(t *testing.T) {
+	ca := jellotest.NewCertificate(t, jellotest.CertOptions{
+		CommonName: "Jello Test CA",
+		IsCA:       true,
+		NotAfter:   time.Now().Add(365 * 24 * time.Hour),
+	})
+	leaf := jellotest.NewCertificate(t, jellotest.CertOptions{
+		CommonName: "api.jello.test",
+		Hosts:      []string{"api.jello.test", "127.0.0.1"},
+		NotAfter:   time.Now().Add(10 * 24 * time.Hour),
+		Parent:     &ca,
+	})
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	srv := newServer(t, leaf)
+
+	tests := []struct {
+		name         string
+		opts         Options
+		wantVerified bool
+		wantWarnings []string
+	}{
+		{
+			name:         "trusted, expiring soon",
+			opts:         Options{RootCAs: roots},
+			wantVerified: true,
+			wantWarnings: []string{"certificate 0 (CN=api.jello.test) expires in 9 days"},
+		},
+		{
+			name:         "expiry warnings off",
+			opts:         Options{RootCAs: roots, WarnWithin: -1},
+			wantVerified: true,
+		},
+		{
+			name:         "within a shorter window",
+			opts:         Options{RootCAs: roots, WarnWithin: 7 * 24 * time.Hour},
+			wantVerified: true,
+		},
+		{
+			name:         "server name",
+			opts:         Options{RootCAs: roots, ServerName: "api.jello.test", WarnWithin: -1},
+			wantVerified: true,
+		},
+		{
+			name:         "wrong server name",
+			opts:         Options{RootCAs: roots, ServerName: "other.jello.test", WarnWithin: -1},
+			wantWarnings: []string{"chain does not verify"},
+		},
+		{
+			name:         "untrusted",
+			opts:         Options{WarnWithin: -1},
+			wantWarnings: []string{"chain does not verify"},
+		},
+		{
+			name: "expired",
+			opts: Options{RootCAs: roots, Now: func() time.Time { return time.Now().Add(30 * 24 * time.Hour) }},
+			wantWarnings: []string{
+				"chain does not verify",
+				"certificate 0 (CN=api.jello.test) expired on",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Inspect(context.Background(), srv.URL, &tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Verified != tt.wantVerified {
+				t.Errorf("verified = %v (%s), want %v", report.Verified, report.VerifyError, tt.wantVerified)
+			}
+			if len(report.Warnings) != len(tt.wantWarnings) {
+				t.Fatalf("warnings = %q, want %q", report.Warnings, tt.wantWarnings)
+			}
+			for i, w := range tt.wantWarnings {
+				if !strings.HasPrefix(report.Warnings[i], w) {
+					t.Errorf("warning %d = %q, want %q...", i, report.Warnings[i], w)
+				}
+			}
+		})
+	}
+}
+
+func TestInspectChain(t *testing.T) {
+	ca := jellotest.NewCertificate(t, jellotest.CertOptions{CommonName: "Jello Test CA", IsCA: true})
+	leaf := jellotest.NewCertificate(t, jellotest.CertOptions{
+		CommonName: "api.jello.test",
+		Hosts:      []string{"api.jello.test", "127.0.0.1"},
+		Parent:     &ca,
+	})
+	srv := newServer(t, leaf)
+
+	report, err := Inspect(context.Background(), srv.Listener.Addr().String(), &Options{ALPN: []string{"http/1.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Chain) != 2 {
+		t.Fatalf("chain has %d certificates, want 2", len(report.Chain))
+	}
+	got := report.Chain[0]
+	if got.Subject != "CN=api.jello.test" || got.Issuer != "CN=Jello Test CA" {
+		t.Errorf("subject, issuer = %q, %q", got.Subject, got.Issuer)
+	}
+	if strings.Join(got.SANs, ",") != "api.jello.test,127.0.0.1" {
+		t.Errorf("sans = %q", got.SANs)
+	}
+	if got.KeyType != "ECDSA P-256" || got.SPKIHash != jello.SPKIHash(leaf.Leaf) || got.IsCA {
+		t.Errorf("leaf = %+v", got)
+	}
+	if !report.Chain[1].IsCA {
+		t.Error("second certificate is not a CA")
+	}
+	if report.ALPN != "http/1.1" || report.Version != "TLS 1.3" || report.ServerName != "127.0.0.1" {
+		t.Errorf("alpn, version, server name = %q, %q, %q", report.ALPN, report.Version, report.ServerName)
+	}
+}
+
+func TestInspectNoTLS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := Inspect(ctx, srv.Listener.Addr().String(), nil); err == nil {
+		t.Error("Inspect of a plain HTTP server succeeded")
+	}
+}
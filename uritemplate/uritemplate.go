@@ -0,0 +1,380 @@
+// Package uritemplate expands RFC 6570 URI templates, levels 1 to 4, so
+// endpoints can be declared once, e.g.
+//
+//	/v{version}/courses_rest_api/learn-http/locations/{id}
+//
+// and have their variables escaped according to where they appear.
+package uritemplate
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Pair is one entry of an associative array value whose order matters.
+type Pair struct {
+	Key, Value string
+}
+
+// Values are the variables to expand a template with. Each value is a
+// string; a []string list; a map[string]string or []Pair associative
+// array (maps are expanded in key order); or anything else, which is
+// formatted with fmt.Sprint. Missing and nil values, empty lists and
+// empty maps are undefined and left out.
+type Values map[string]any
+
+// Template is a parsed URI template.
+type Template struct {
+	raw   string
+	parts []part
+}
+
+// part is a literal, or an expression if op is set. Expressions with
+// no operator use opSimple.
+type part struct {
+	literal string
+	op      *operator
+	vars    []varspec
+}
+
+type varspec struct {
+	name    string
+	prefix  int // 0 for no prefix modifier
+	explode bool
+}
+
+// operator describes an expression operator, from the table in
+// appendix A of RFC 6570.
+type operator struct {
+	first    string
+	sep      string
+	named    bool
+	ifEmpty  string
+	reserved bool // allow reserved characters and pct-encoded triplets
+}
+
+var (
+	opSimple  = &operator{first: "", sep: ","}
+	operators = map[byte]*operator{
+		'+': {first: "", sep: ",", reserved: true},
+		'#': {first: "#", sep: ",", reserved: true},
+		'.': {first: ".", sep: "."},
+		'/': {first: "/", sep: "/"},
+		';': {first: ";", sep: ";", named: true},
+		'?': {first: "?", sep: "&", named: true, ifEmpty: "="},
+		'&': {first: "&", sep: "&", named: true, ifEmpty: "="},
+	}
+)
+
+// Parse parses a template.
+func Parse(s string) (*Template, error) {
+	t := &Template{raw: s}
+	for s != "" {
+		open := strings.IndexAny(s, "{}")
+		if open < 0 {
+			t.parts = append(t.parts, part{literal: s})
+			break
+		}
+		if s[open] == '}' {
+			return nil, fmt.Errorf("uritemplate: unmatched } in %q", t.raw)
+		}
+		if open > 0 {
+			t.parts = append(t.parts, part{literal: s[:open]})
+		}
+		end := strings.IndexByte(s[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("uritemplate: unclosed { in %q", t.raw)
+		}
+		p, err := parseExpression(s[open+1 : open+end])
+		if err != nil {
+			return nil, fmt.Errorf("uritemplate: %w in %q", err, t.raw)
+		}
+		t.parts = append(t.parts, p)
+		s = s[open+end+1:]
+	}
+	for _, p := range t.parts {
+		if p.op == nil {
+			if err := checkLiteral(p.literal); err != nil {
+				return nil, fmt.Errorf("uritemplate: %w in %q", err, t.raw)
+			}
+		}
+	}
+	return t, nil
+}
+
+// MustParse is like Parse but panics on an error, for templates
+// declared as package variables.
+func MustParse(s string) *Template {
+	t, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// String returns the template as it was parsed.
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Names returns the names of the template's variables, in order, once
+// each.
+func (t *Template) Names() []string {
+	var names []string
+	for _, p := range t.parts {
+		for _, v := range p.vars {
+			if !slices.Contains(names, v.name) {
+				names = append(names, v.name)
+			}
+		}
+	}
+	return names
+}
+
+func parseExpression(expr string) (part, error) {
+	if expr == "" {
+		return part{}, fmt.Errorf("empty expression")
+	}
+	p := part{op: opSimple}
+	if op, ok := operators[expr[0]]; ok {
+		p.op = op
+		expr = expr[1:]
+	} else if strings.ContainsRune("=,!@|", rune(expr[0])) {
+		return part{}, fmt.Errorf("reserved operator %q", expr[0])
+	}
+	for _, spec := range strings.Split(expr, ",") {
+		var v varspec
+		switch name, prefix, ok := strings.Cut(spec, ":"); {
+		case ok:
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n < 1 || n > 9999 || prefix[0] == '0' {
+				return part{}, fmt.Errorf("invalid prefix %q", spec)
+			}
+			v.name, v.prefix = name, n
+		case strings.HasSuffix(spec, "*"):
+			v.name, v.explode = strings.TrimSuffix(spec, "*"), true
+		default:
+			v.name = spec
+		}
+		if !validName(v.name) {
+			return part{}, fmt.Errorf("invalid variable name %q", v.name)
+		}
+		p.vars = append(p.vars, v)
+	}
+	return p, nil
+}
+
+// validName checks varname from section 2.3: varchars separated by
+// single dots, where a varchar is ALPHA, DIGIT, _ or a pct-encoded
+// triplet.
+func validName(name string) bool {
+	if name == "" || name[0] == '.' || name[len(name)-1] == '.' || strings.Contains(name, "..") {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case isAlpha(c) || isDigit(c) || c == '_' || c == '.':
+		case c == '%' && i+2 < len(name) && isHex(name[i+1]) && isHex(name[i+2]):
+			i += 2
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// checkLiteral rejects characters section 2.1 doesn't allow outside
+// expressions.
+func checkLiteral(s string) error {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '%':
+			if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+				return fmt.Errorf("invalid percent encoding in literal")
+			}
+		case c <= ' ' || c == 0x7f || strings.IndexByte(`"'<>\^`+"`|", c) >= 0:
+			return fmt.Errorf("invalid character %q in literal", c)
+		}
+	}
+	return nil
+}
+
+// Expand expands the template with vars.
+func (t *Template) Expand(vars Values) (string, error) {
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.op == nil {
+			b.WriteString(encode(p.literal, true))
+			continue
+		}
+		if err := p.expand(&b, vars); err != nil {
+			return "", fmt.Errorf("uritemplate: %w", err)
+		}
+	}
+	return b.String(), nil
+}
+
+func (p part) expand(b *strings.Builder, vars Values) error {
+	op := p.op
+	first := true
+	for _, v := range p.vars {
+		value, ok := vars[v.name]
+		if !ok || value == nil {
+			continue
+		}
+
+		var (
+			list  []string
+			pairs []Pair
+			str   string
+		)
+		isList, isMap := false, false
+		switch value := value.(type) {
+		case string:
+			str = value
+		case []string:
+			list, isList = value, true
+		case []Pair:
+			pairs, isMap = value, true
+		case map[string]string:
+			isMap = true
+			for _, k := range slices.Sorted(maps.Keys(value)) {
+				pairs = append(pairs, Pair{k, value[k]})
+			}
+		default:
+			str = fmt.Sprint(value)
+		}
+		if isList && len(list) == 0 || isMap && len(pairs) == 0 {
+			continue
+		}
+		if (isList || isMap) && v.prefix > 0 {
+			return fmt.Errorf("prefix modifier used on composite variable %q", v.name)
+		}
+
+		if first {
+			b.WriteString(op.first)
+			first = false
+		} else {
+			b.WriteString(op.sep)
+		}
+		name := encode(v.name, true)
+		named := func(value string) {
+			b.WriteString(name)
+			if value == "" {
+				b.WriteString(op.ifEmpty)
+				return
+			}
+			b.WriteString("=")
+			b.WriteString(value)
+		}
+
+		switch {
+		case !isList && !isMap:
+			if v.prefix > 0 {
+				str = truncate(str, v.prefix)
+			}
+			if op.named {
+				named(encode(str, op.reserved))
+			} else {
+				b.WriteString(encode(str, op.reserved))
+			}
+
+		case !v.explode:
+			var items []string
+			if isList {
+				for _, item := range list {
+					items = append(items, encode(item, op.reserved))
+				}
+			} else {
+				for _, kv := range pairs {
+					items = append(items, encode(kv.Key, op.reserved), encode(kv.Value, op.reserved))
+				}
+			}
+			joined := strings.Join(items, ",")
+			if op.named {
+				named(joined)
+			} else {
+				b.WriteString(joined)
+			}
+
+		case isList:
+			for i, item := range list {
+				if i > 0 {
+					b.WriteString(op.sep)
+				}
+				if op.named {
+					named(encode(item, op.reserved))
+				} else {
+					b.WriteString(encode(item, op.reserved))
+				}
+			}
+
+		default:
+			for i, kv := range pairs {
+				if i > 0 {
+					b.WriteString(op.sep)
+				}
+				b.WriteString(encode(kv.Key, op.reserved))
+				if op.named && kv.Value == "" {
+					b.WriteString(op.ifEmpty)
+					continue
+				}
+				b.WriteString("=")
+				b.WriteString(encode(kv.Value, op.reserved))
+			}
+		}
+	}
+	return nil
+}
+
+// truncate returns the first n characters of s.
+func truncate(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// encode percent-encodes s, leaving unreserved characters alone and,
+// if reserved is set, reserved characters and existing pct-encoded
+// triplets too.
+func encode(s string, reserved bool) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case isUnreserved(c):
+			b.WriteByte(c)
+		case reserved && isReserved(c):
+			b.WriteByte(c)
+		case reserved && c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteString(s[i : i+3])
+			i += 2
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0xf])
+		}
+	}
+	return b.String()
+}
+
+func isAlpha(c byte) bool { return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+func isHex(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+func isUnreserved(c byte) bool {
+	return isAlpha(c) || isDigit(c) || c == '-' || c == '.' || c == '_' || c == '~'
+}
+func isReserved(c byte) bool {
+	return strings.IndexByte(":/?#[]@!$&'()*+,;=", c) >= 0
+}
@@ -0,0 +1,361 @@
+package uritemplate
+
+import (
+	"slices"
+	"testing"
+)
+
+// rfcValues are the variables of the examples in sections 1.2 and 3.2
+// of RFC 6570.
+var rfcValues = Values{
+	"count":      []string{"one", "two", "three"},
+	"dom":        []string{"example", "com"},
+	"dub":        "me/too",
+	"hello":      "Hello World!",
+	"half":       "50%",
+	"var":        "value",
+	"who":        "fred",
+	"base":       "http://example.com/home/",
+	"path":       "/foo/bar",
+	"list":       []string{"red", "green", "blue"},
+	"keys":       []Pair{{"semi", ";"}, {"dot", "."}, {"comma", ","}},
+	"v":          6,
+	"x":          1024,
+	"y":          768,
+	"empty":      "",
+	"empty_keys": []Pair{},
+	"undef":      nil,
+}
+
+func testExpand(t *testing.T, tests []struct{ template, want string }) {
+	t.Helper()
+	for _, tt := range tests {
+		tmpl, err := Parse(tt.template)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.template, err)
+			continue
+		}
+		got, err := tmpl.Expand(rfcValues)
+		if err != nil {
+			t.Errorf("Expand(%q): %v", tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+// TestLevels checks the examples of each level from section 1.2.
+func TestLevels(t *testing.T) {
+	t.Run("level 1", func(t *testing.T) {
+		testExpand(t, []struct{ template, want string }{
+			{"{var}", "value"},
+			{"{hello}", "Hello%20World%21"},
+		})
+	})
+	t.Run("level 2", func(t *testing.T) {
+		testExpand(t, []struct{ template, want string }{
+			{"{+var}", "value"},
+			{"{+hello}", "Hello%20World!"},
+			{"{+path}/here", "/foo/bar/here"},
+			{"here?ref={+path}", "here?ref=/foo/bar"},
+			{"X{#var}", "X#value"},
+			{"X{#hello}", "X#Hello%20World!"},
+		})
+	})
+	t.Run("level 3", func(t *testing.T) {
+		testExpand(t, []struct{ template, want string }{
+			{"map?{x,y}", "map?1024,768"},
+			{"{x,hello,y}", "1024,Hello%20World%21,768"},
+			{"{+x,hello,y}", "1024,Hello%20World!,768"},
+			{"{+path,x}/here", "/foo/bar,1024/here"},
+			{"{#x,hello,y}", "#1024,Hello%20World!,768"},
+			{"{#path,x}/here", "#/foo/bar,1024/here"},
+			{"X{.var}", "X.value"},
+			{"X{.x,y}", "X.1024.768"},
+			{"{/var}", "/value"},
+			{"{/var,x}/here", "/value/1024/here"},
+			{"{;x,y}", ";x=1024;y=768"},
+			{"{;x,y,empty}", ";x=1024;y=768;empty"},
+			{"{?x,y}", "?x=1024&y=768"},
+			{"{?x,y,empty}", "?x=1024&y=768&empty="},
+			{"?fixed=yes{&x}", "?fixed=yes&x=1024"},
+			{"{&x,y,empty}", "&x=1024&y=768&empty="},
+		})
+	})
+	t.Run("level 4", func(t *testing.T) {
+		testExpand(t, []struct{ template, want string }{
+			{"{var:3}", "val"},
+			{"{var:30}", "value"},
+			{"{list}", "red,green,blue"},
+			{"{list*}", "red,green,blue"},
+			{"{keys}", "semi,%3B,dot,.,comma,%2C"},
+			{"{keys*}", "semi=%3B,dot=.,comma=%2C"},
+			{"{+path:6}/here", "/foo/b/here"},
+			{"{+list}", "red,green,blue"},
+			{"{+list*}", "red,green,blue"},
+			{"{+keys}", "semi,;,dot,.,comma,,"},
+			{"{+keys*}", "semi=;,dot=.,comma=,"},
+			{"{#path:6}/here", "#/foo/b/here"},
+			{"{#list}", "#red,green,blue"},
+			{"{#list*}", "#red,green,blue"},
+			{"{#keys}", "#semi,;,dot,.,comma,,"},
+			{"{#keys*}", "#semi=;,dot=.,comma=,"},
+			{"X{.var:3}", "X.val"},
+			{"X{.list}", "X.red,green,blue"},
+			{"X{.list*}", "X.red.green.blue"},
+			{"X{.keys}", "X.semi,%3B,dot,.,comma,%2C"},
+			{"X{.keys*}", "X.semi=%3B.dot=..comma=%2C"},
+			{"{/var:1,var}", "/v/value"},
+			{"{/list}", "/red,green,blue"},
+			{"{/list*}", "/red/green/blue"},
+			{"{/list*,path:4}", "/red/green/blue/%2Ffoo"},
+			{"{/keys}", "/semi,%3B,dot,.,comma,%2C"},
+			{"{/keys*}", "/semi=%3B/dot=./comma=%2C"},
+			{"{;hello:5}", ";hello=Hello"},
+			{"{;list}", ";list=red,green,blue"},
+			{"{;list*}", ";list=red;list=green;list=blue"},
+			{"{;keys}", ";keys=semi,%3B,dot,.,comma,%2C"},
+			{"{;keys*}", ";semi=%3B;dot=.;comma=%2C"},
+			{"{?var:3}", "?var=val"},
+			{"{?list}", "?list=red,green,blue"},
+			{"{?list*}", "?list=red&list=green&list=blue"},
+			{"{?keys}", "?keys=semi,%3B,dot,.,comma,%2C"},
+			{"{?keys*}", "?semi=%3B&dot=.&comma=%2C"},
+			{"{&var:3}", "&var=val"},
+			{"{&list}", "&list=red,green,blue"},
+			{"{&list*}", "&list=red&list=green&list=blue"},
+			{"{&keys}", "&keys=semi,%3B,dot,.,comma,%2C"},
+			{"{&keys*}", "&semi=%3B&dot=.&comma=%2C"},
+		})
+	})
+}
+
+// TestOperators checks the examples for each operator from section 3.2.
+func TestOperators(t *testing.T) {
+	t.Run("3.2.1 variable expansion", func(t *testing.T) {
+		testExpand(t, []struct{ template, want string }{
+			{"{count}", "one,two,three"},
+			{"{count*}", "one,two,three"},
+			{"{/count}", "/one,two,three"},
+			{"{/count*}", "/one/two/three"},
+			{"{;count}", ";count=one,two,three"},
+			{"{;count*}", ";count=one;count=two;count=three"},
+			{"{?count}", "?count=one,two,three"},
+			{"{?count*}", "?count=one&count=two&count=three"},
+			{"{&count*}", "&count=one&count=two&count=three"},
+		})
+	})
+	t.Run("3.2.2 simple string expansion", func(t *testing.T) {
+		testExpand(t, []struct{ template, want string }{
+			{"{var}", "value"},
+			{"{hello}", "Hello%20World%21"},
+			{"{half}", "50%25"},
+			{"O{empty}X", "OX"},
+			{"O{undef}X", "OX"},
+			{"{x,y}", "1024,768"},
+			{"{x,hello,y}", "1024,Hello%20World%21,768"},
+			{"?{x,empty}", "?1024,"},
+			{"?{x,undef}", "?1024"},
+			{"?{undef,y}", "?768"},
+			{"{var:3}", "val"},
+			{"{var:30}", "value"},
+			{"{list}", "red,green,blue"},
+			{"{list*}", "red,green,blue"},
+			{"{keys}", "semi,%3B,dot,.,comma,%2C"},
+			{"{keys*}", "semi=%3B,dot=.,comma=%2C"},
+		})
+	})
+	t.Run("3.2.3 reserved expansion", func(t *testing.T) {
+		testExpand(t, []struct{ template, want string }{
+			{"{+var}", "value"},
+			{"{+hello}", "Hello%20World!"},
+			{"{+half}", "50%25"},
+			{"{base}index", "http%3A%2F%2Fexample.com%2Fhome%2Findex"},
+			{"{+base}index", "http://example.com/home/index"},
+			{"O{+empty}X", "OX"},
+			{"O{+undef}X", "OX"},
+			{"{+path}/here", "/foo/bar/here"},
+			{"here?ref={+path}", "here?ref=/foo/bar"},
+			{"up{+path}{var}/here", "up/foo/barvalue/here"},
+			{"{+x,hello,y}", "1024,Hello%20World!,768"},
+			{"{+path,x}/here", "/foo/bar,1024/here"},
+			{"{+path:6}/here", "/foo/b/here"},
+			{"{+list}", "red,green,blue"},
+			{"{+list*}", "red,green,blue"},
+			{"{+keys}", "semi,;,dot,.,comma,,"},
+			{"{+keys*}", "semi=;,dot=.,comma=,"},
+		})
+	})
+	t.Run("3.2.4 fragment expansion", func(t *testing.T) {
+		testExpand(t, []struct{ template, want string }{
+			{"{#var}", "#value"},
+			{"{#hello}", "#Hello%20World!"},
+			{"{#half}", "#50%25"},
+			{"foo{#empty}", "foo#"},
+			{"foo{#undef}", "foo"},
+			{"{#x,hello,y}", "#1024,Hello%20World!,768"},
+			{"{#path,x}/here", "#/foo/bar,1024/here"},
+			{"{#path:6}/here", "#/foo/b/here"},
+			{"{#list}", "#red,green,blue"},
+			{"{#list*}", "#red,green,blue"},
+			{"{#keys}", "#semi,;,dot,.,comma,,"},
+			{"{#keys*}", "#semi=;,dot=.,comma=,"},
+		})
+	})
+	t.Run("3.2.5 label expansion", func(t *testing.T) {
+		testExpand(t, []struct{ template, want string }{
+			{"{.who}", ".fred"},
+			{"{.who,who}", ".fred.fred"},
+			{"{.half,who}", ".50%25.fred"},
+			{"www{.dom*}", "www.example.com"},
+			{"X{.var}", "X.value"},
+			{"X{.empty}", "X."},
+			{"X{.undef}", "X"},
+			{"X{.var:3}", "X.val"},
+			{"X{.list}", "X.red,green,blue"},
+			{"X{.list*}", "X.red.green.blue"},
+			{"X{.keys}", "X.semi,%3B,dot,.,comma,%2C"},
+			{"X{.keys*}", "X.semi=%3B.dot=..comma=%2C"},
+			{"X{.empty_keys}", "X"},
+			{"X{.empty_keys*}", "X"},
+		})
+	})
+	t.Run("3.2.6 path segment expansion", func(t *testing.T) {
+		testExpand(t, []struct{ template, want string }{
+			{"{/who}", "/fred"},
+			{"{/who,who}", "/fred/fred"},
+			{"{/half,who}", "/50%25/fred"},
+			{"{/who,dub}", "/fred/me%2Ftoo"},
+			{"{/var}", "/value"},
+			{"{/var,empty}", "/value/"},
+			{"{/var,undef}", "/value"},
+			{"{/var,x}/here", "/value/1024/here"},
+			{"{/var:1,var}", "/v/value"},
+			{"{/list}", "/red,green,blue"},
+			{"{/list*}", "/red/green/blue"},
+			{"{/list*,path:4}", "/red/green/blue/%2Ffoo"},
+			{"{/keys}", "/semi,%3B,dot,.,comma,%2C"},
+			{"{/keys*}", "/semi=%3B/dot=./comma=%2C"},
+		})
+	})
+	t.Run("3.2.7 path-style parameter expansion", func(t *testing.T) {
+		testExpand(t, []struct{ template, want string }{
+			{"{;who}", ";who=fred"},
+			{"{;half}", ";half=50%25"},
+			{"{;empty}", ";empty"},
+			{"{;v,empty,who}", ";v=6;empty;who=fred"},
+			{"{;v,bar,who}", ";v=6;who=fred"},
+			{"{;x,y}", ";x=1024;y=768"},
+			{"{;x,y,empty}", ";x=1024;y=768;empty"},
+			{"{;x,y,undef}", ";x=1024;y=768"},
+			{"{;hello:5}", ";hello=Hello"},
+			{"{;list}", ";list=red,green,blue"},
+			{"{;list*}", ";list=red;list=green;list=blue"},
+			{"{;keys}", ";keys=semi,%3B,dot,.,comma,%2C"},
+			{"{;keys*}", ";semi=%3B;dot=.;comma=%2C"},
+		})
+	})
+	t.Run("3.2.8 form-style query expansion", func(t *testing.T) {
+		testExpand(t, []struct{ template, want string }{
+			{"{?who}", "?who=fred"},
+			{"{?half}", "?half=50%25"},
+			{"{?x,y}", "?x=1024&y=768"},
+			{"{?x,y,empty}", "?x=1024&y=768&empty="},
+			{"{?x,y,undef}", "?x=1024&y=768"},
+			{"{?var:3}", "?var=val"},
+			{"{?list}", "?list=red,green,blue"},
+			{"{?list*}", "?list=red&list=green&list=blue"},
+			{"{?keys}", "?keys=semi,%3B,dot,.,comma,%2C"},
+			{"{?keys*}", "?semi=%3B&dot=.&comma=%2C"},
+		})
+	})
+	t.Run("3.2.9 form-style query continuation", func(t *testing.T) {
+		testExpand(t, []struct{ template, want string }{
+			{"{&who}", "&who=fred"},
+			{"{&half}", "&half=50%25"},
+			{"?fixed=yes{&x}", "?fixed=yes&x=1024"},
+			{"{&x,y,empty}", "&x=1024&y=768&empty="},
+			{"{&var:3}", "&var=val"},
+			{"{&list}", "&list=red,green,blue"},
+			{"{&list*}", "&list=red&list=green&list=blue"},
+			{"{&keys}", "&keys=semi,%3B,dot,.,comma,%2C"},
+			{"{&keys*}", "&semi=%3B&dot=.&comma=%2C"},
+		})
+	})
+}
+
+func TestExpandValues(t *testing.T) {
+	tests := []struct {
+		template string
+		vars     Values
+		want     string
+	}{
+		// Prefixes count characters, not bytes.
+		{"{var:2}", Values{"var": "héllo"}, "h%C3%A9"},
+		{"{+var:3}", Values{"var": "%20ab"}, "%20"},
+		// Maps are expanded in key order.
+		{"{?m*}", Values{"m": map[string]string{"b": "2", "a": "1"}}, "?a=1&b=2"},
+		{"{?m}", Values{"m": map[string]string{}}, ""},
+		{"{;p*}", Values{"p": []Pair{{"k", ""}}}, ";k"},
+		{"{?l}", Values{"l": []string{}}, ""},
+		{"{x}", Values{"x": 1.5}, "1.5"},
+		{"/caf%C3%A9/{id}", Values{"id": "a b"}, "/caf%C3%A9/a%20b"},
+	}
+	for _, tt := range tests {
+		got, err := MustParse(tt.template).Expand(tt.vars)
+		if err != nil {
+			t.Errorf("Expand(%q): %v", tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	for _, tmpl := range []string{"{list:3}", "{keys:1}"} {
+		if got, err := MustParse(tmpl).Expand(rfcValues); err == nil {
+			t.Errorf("Expand(%q) = %q, want error", tmpl, got)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"{",
+		"}",
+		"{var",
+		"var}",
+		"{}",
+		"{=var}",
+		"{!var}",
+		"{var:0}",
+		"{var:01}",
+		"{var:10000}",
+		"{var:x}",
+		"{.}",
+		"{a..b}",
+		"{a-b}",
+		"{var,}",
+		"{%zz}",
+		"a b{var}",
+		"a<{var}",
+		"100%{var}",
+	}
+	for _, s := range tests {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	got := MustParse("/v{version}/issues{/id,version}{?state,tag*}{&page:2}").Names()
+	want := []string{"version", "id", "state", "tag", "page"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Names() = %q, want %q", got, want)
+	}
+}